@@ -1,8 +1,18 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go-user-api/internal/handlers"
 	"go-user-api/internal/routes"
+	"go-user-api/internal/server"
 )
 
 func main() {
@@ -14,7 +24,27 @@ func main() {
 	})
 
 	// register user routes
-	routes.UserRoutes(r)
+	routes.UserRoutes(r, handlers.NewUserHandler(time.Now))
+
+	ln, err := net.Listen("tcp", ":8080")
+	if err != nil {
+		log.Fatal("Listen error:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// serve until a signal arrives, then drain in-flight requests
+	if err := server.Run(ctx, r, ln, shutdownTimeout()); err != nil {
+		log.Fatal("Server error:", err)
+	}
+	log.Println("Server stopped")
+}
 
-	r.Run(":8080")
+// shutdownTimeout reads SHUTDOWN_TIMEOUT, defaulting to 30s
+func shutdownTimeout() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
 }
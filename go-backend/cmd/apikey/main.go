@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"user-api/config"
+	"user-api/internal/logger"
+	"user-api/internal/models"
+	"user-api/internal/rbac"
+	"user-api/internal/repository"
+	"user-api/internal/service"
+	"user-api/internal/validation"
+)
+
+const usage = `Usage: apikey <command>
+
+Commands:
+  create <name> [scope...]  mint a key and print it once; without scopes the
+                            key holds every scope, including api_keys:manage`
+
+// allScopes are granted to keys created without explicit scopes, so the
+// first key can manage the others
+var allScopes = []string{rbac.PermUsersRead, rbac.PermUsersWrite, rbac.PermUsersDelete, rbac.PermAPIKeysManage}
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	if len(os.Args) < 3 || os.Args[1] != "create" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	zapLogger := logger.NewLogger()
+	defer zapLogger.Sync()
+
+	req := &models.CreateAPIKeyRequest{Name: os.Args[2], Scopes: os.Args[3:]}
+	if len(req.Scopes) == 0 {
+		req.Scopes = allScopes
+	}
+	validator, err := validation.New("")
+	if err != nil {
+		zapLogger.Fatal("Failed to load validation messages", err)
+	}
+	if err := validator.Struct(req); err != nil {
+		var messages []string
+		for _, message := range validator.Errors(err, "en") {
+			messages = append(messages, message)
+		}
+		zapLogger.Fatal("Invalid API key", fmt.Errorf("%s", strings.Join(messages, "; ")))
+	}
+
+	db, err := config.NewDBConnection()
+	if err != nil {
+		zapLogger.Fatal("Failed to connect to database", err)
+	}
+	defer db.Close()
+
+	// Whoever can reach the database may mint any scope
+	grantAll := func(string) bool { return true }
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), zapLogger)
+	created, err := apiKeyService.CreateAPIKey(context.Background(), req, grantAll)
+	if err != nil {
+		zapLogger.Fatal("Failed to create API key", err)
+	}
+
+	// The plaintext is not stored, so this is the only time it is shown
+	fmt.Printf("id:     %d\nname:   %s\nscopes: %s\nkey:    %s\n", created.ID, created.Name, strings.Join(created.Scopes, ","), created.Key)
+}
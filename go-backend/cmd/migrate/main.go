@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+
+	"user-api/config"
+	"user-api/db/migrations"
+	"user-api/internal/logger"
+	"user-api/internal/migrate"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up              apply all pending migrations
+  down [steps]    revert the last applied migration, or the last steps
+  goto <version>  migrate up or down to version (0 reverts everything)
+  status          list migrations and whether they are applied`
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	zapLogger := logger.NewLogger()
+	defer zapLogger.Sync()
+
+	db, err := config.NewDBConnection()
+	if err != nil {
+		zapLogger.Fatal("Failed to connect to database", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrations.FS, zapLogger)
+	if err != nil {
+		zapLogger.Fatal("Failed to load migrations", err)
+	}
+
+	ctx := context.Background()
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			zapLogger.Fatal("Failed to apply migrations", err)
+		}
+		zapLogger.Info("Migrations applied", zap.Int("applied", applied))
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				zapLogger.Fatal("Invalid number of steps", fmt.Errorf("%q is not a positive integer", args[0]))
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			zapLogger.Fatal("Failed to revert migrations", err)
+		}
+		zapLogger.Info("Migrations reverted", zap.Int("reverted", reverted))
+
+	case "goto":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || version < 0 {
+			zapLogger.Fatal("Invalid version", fmt.Errorf("%q is not a migration version", args[0]))
+		}
+		changed, err := migrator.Goto(ctx, version)
+		if err != nil {
+			zapLogger.Fatal("Failed to migrate", err)
+		}
+		zapLogger.Info("Migrated", zap.Int64("version", version), zap.Int("changed", changed))
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			zapLogger.Fatal("Failed to read migration status", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (modified)"
+			}
+			fmt.Printf("%03d_%-20s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
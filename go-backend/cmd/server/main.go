@@ -1,21 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	// Embed the time zone database for the tz parameter and AGE_TIMEZONE,
+	// as the runtime image has none
+	_ "time/tzdata"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/joho/godotenv"
+	"go.uber.org/zap"
 
 	"user-api/config"
+	"user-api/db/migrations"
+	"user-api/internal/auth"
+	"user-api/internal/clock"
+	"user-api/internal/cursor"
 	"user-api/internal/handler"
+	"user-api/internal/health"
+	"user-api/internal/jobs"
 	"user-api/internal/logger"
+	"user-api/internal/metrics"
 	"user-api/internal/middleware"
+	"user-api/internal/migrate"
+	"user-api/internal/ratelimit"
+	"user-api/internal/rbac"
 	"user-api/internal/repository"
 	"user-api/internal/routes"
+	"user-api/internal/server"
 	"user-api/internal/service"
+	"user-api/internal/tracing"
+	"user-api/internal/validation"
 )
 
 func main() {
@@ -24,37 +47,202 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	// Initialize logger
+	// Initialize logger; buffered entries are flushed after shutdown
 	zapLogger := logger.NewLogger()
 	defer zapLogger.Sync()
 
+	// Shut down gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	shutdownCfg, err := config.LoadShutdownConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load shutdown configuration", err)
+	}
+
+	// Export traces
+	tracingCfg, err := config.LoadTracingConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load tracing configuration", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    tracingCfg.Exporter,
+		File:        tracingCfg.File,
+		ServiceName: tracingCfg.ServiceName,
+		SampleRatio: tracingCfg.SampleRatio,
+	})
+	if err != nil {
+		zapLogger.Fatal("Failed to set up tracing", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			zapLogger.Error("Failed to flush traces", zap.Error(err))
+		}
+	}()
+
 	// Initialize database connection
 	db, err := config.NewDBConnection()
 	if err != nil {
 		zapLogger.Fatal("Failed to connect to database", err)
 	}
-	defer db.Close()
+	defer func() {
+		if err := db.Close(); err != nil {
+			zapLogger.Error("Failed to close database", zap.Error(err))
+		}
+	}()
 
 	zapLogger.Info("Database connection established")
 
+	// Apply pending schema migrations
+	migrator, err := migrate.New(db, migrations.FS, zapLogger)
+	if err != nil {
+		zapLogger.Fatal("Failed to load migrations", err)
+	}
+	if config.LoadMigrationConfig().RunOnStart {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			zapLogger.Fatal("Failed to apply migrations", err)
+		}
+		zapLogger.Info("Schema is up to date", zap.Int("applied", applied), zap.Int64("version", migrator.Latest()))
+	}
+
+	// Load cursor signing secret
+	cursorCfg, err := config.LoadCursorConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load cursor configuration", err)
+	}
+	if cursorCfg.Generated {
+		zapLogger.Warn("CURSOR_SECRET not set, using a random secret; cursors will not survive restarts")
+	}
+
+	// Load the default time zone for age calculation
+	ageCfg, err := config.LoadAgeConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load age configuration", err)
+	}
+
 	// Initialize layers
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo, zapLogger)
-	userHandler := handler.NewUserHandler(userService, zapLogger)
+	userService := service.NewUserService(userRepo, cursor.NewCodec(cursorCfg.Secret), clock.System, ageCfg.Location, zapLogger)
+
+	// Collect Prometheus metrics and trace service operations
+	var appMetrics *metrics.Metrics
+	if config.LoadMetricsConfig().Enabled {
+		appMetrics = metrics.New()
+		if err := appMetrics.RegisterDB(db, config.LoadDBConfig().DBName); err != nil {
+			zapLogger.Fatal("Failed to register database metrics", err)
+		}
+	}
+	userService = service.NewInstrumentedUserService(userService, appMetrics)
+
+	// Check dependencies for the readiness and startup probes
+	healthCfg, err := config.LoadHealthConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load health configuration", err)
+	}
+	checker := health.NewChecker(healthCfg.CheckTimeout)
+	checker.Register("database", health.Database(db))
+	checker.Register("migrations", health.Migrations(db, migrator.Latest()))
+
+	// Load localised validation messages
+	validator, err := validation.New(config.LoadValidationConfig().TranslationsDir)
+	if err != nil {
+		zapLogger.Fatal("Failed to load validation translations", err)
+	}
+	zapLogger.Info("Validation messages loaded", zap.Strings("locales", validator.Locales()))
+
+	userHandler := handler.NewUserHandler(userService, validator, zapLogger)
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), zapLogger)
+	idempotencyCfg, err := config.LoadIdempotencyConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load idempotency configuration", err)
+	}
+	idempotencyRepo := repository.NewIdempotencyRepository(db, idempotencyCfg.Lease)
+
+	// Start purging expired soft-deleted users
+	retentionCfg, err := config.LoadRetentionConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load retention configuration", err)
+	}
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if retentionCfg.PurgeInterval > 0 {
+		purgeJob := jobs.NewPurgeJob(userService, retentionCfg.PurgeInterval, retentionCfg.SoftDeleteRetention, zapLogger)
+		go purgeJob.Run(jobCtx)
+		go jobs.NewIdempotencyPurgeJob(idempotencyRepo, retentionCfg.PurgeInterval, zapLogger).Run(jobCtx)
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler,
+		ErrorHandler: middleware.NewErrorHandler(zapLogger),
 	})
 
 	// Middleware
 	app.Use(recover.New())
-	app.Use(cors.New())
+	app.Use(cors.New(cors.Config{
+		ExposeHeaders: "ETag, X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After, Idempotent-Replayed",
+	}))
 	app.Use(middleware.RequestID())
+	app.Use(middleware.Tracing())
 	app.Use(middleware.LoggerMiddleware(zapLogger))
+	if appMetrics != nil {
+		app.Use(middleware.Metrics(appMetrics))
+		app.Get("/metrics", adaptor.HTTPHandler(appMetrics.Handler()))
+	}
+
+	// Authenticate API requests by API key or bearer token
+	app.Use("/api/v1", middleware.APIKeyAuth(apiKeyService, zapLogger))
+	authCfg, err := config.LoadAuthConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load auth configuration", err)
+	}
+	if authCfg.Enabled {
+		verifier, err := newVerifier(authCfg)
+		if err != nil {
+			zapLogger.Fatal("Failed to set up authentication", err)
+		}
+		app.Use("/api/v1", middleware.JWTAuth(verifier, zapLogger))
+	} else {
+		zapLogger.Warn("AUTH_ENABLED not set, requests without an API key are unauthenticated")
+	}
+	if config.LoadConcurrencyConfig().RequireIfMatch {
+		app.Use("/api/v1/users", middleware.RequireIfMatch())
+	}
+
+	// Enforce role-based access control
+	rbacCfg, err := config.LoadRBACConfig()
+	if err != nil {
+		zapLogger.Fatal("Failed to load RBAC configuration", err)
+	}
+	var authz *middleware.Authorizer
+	if rbacCfg.Enabled {
+		authz, err = newAuthorizer(rbacCfg, zapLogger)
+		if err != nil {
+			zapLogger.Fatal("Failed to set up access control", err)
+		}
+		if rbacCfg.RoleSource == "claim" && !authCfg.Enabled {
+			zapLogger.Warn("RBAC reads roles from token claims but AUTH_ENABLED is not set; every request will be denied")
+		}
+	}
+
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, validator, authz.Holds, zapLogger)
+
+	// Rate limit each client per route
+	rateLimitCfg, err := config.LoadRateLimitConfig(routes.RouteNames)
+	if err != nil {
+		zapLogger.Fatal("Failed to load rate limit configuration", err)
+	}
+	var limiter *middleware.RateLimiter
+	if rateLimitCfg.Enabled {
+		limiter = middleware.NewRateLimiter(ratelimit.NewMemoryStore(), rateLimitCfg.Default, rateLimitCfg.Routes, zapLogger)
+	}
 
 	// Setup routes
-	routes.SetupRoutes(app, userHandler)
+	// Replay responses to retried POST requests once they pass RBAC and
+	// rate limiting
+	idempotency := middleware.Idempotency(idempotencyRepo, idempotencyCfg.TTL, zapLogger)
+	routes.SetupRoutes(app, userHandler, apiKeyHandler, handler.NewHealthHandler(checker), authz, limiter, idempotency)
 
 	// Get port from environment
 	port := os.Getenv("PORT")
@@ -62,10 +250,65 @@ func main() {
 		port = "3000"
 	}
 
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		zapLogger.Fatal("Failed to start server", err)
+	}
 	zapLogger.Info("Starting server on port " + port)
 
-	// Start server
-	if err := app.Listen(":" + port); err != nil {
-		zapLogger.Fatal("Failed to start server", err)
+	// Serve until a signal, then drain in-flight requests. The deferred
+	// calls stop the jobs, close the database, flush traces and sync the
+	// logger in that order.
+	err = server.Run(ctx, app, ln, checker, server.Config{
+		DrainDelay: shutdownCfg.DrainDelay,
+		Timeout:    shutdownCfg.Timeout,
+	}, zapLogger)
+	if err != nil {
+		zapLogger.Error("Server stopped with error", zap.Error(err))
+	}
+}
+
+// newVerifier builds a token verifier from the configured key sources
+func newVerifier(cfg *config.AuthConfig) (*auth.Verifier, error) {
+	var keys []auth.KeySet
+	if len(cfg.HMACSecret) > 0 {
+		keys = append(keys, auth.NewSecretKeySet(cfg.HMACSecret))
+	}
+	if cfg.JWKSFile != "" {
+		fileKeys, err := auth.NewJWKSFileKeySet(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys)
+	}
+	if cfg.JWKSURL != "" {
+		keys = append(keys, auth.NewRemoteKeySet(cfg.JWKSURL, cfg.JWKSCacheTTL, nil))
 	}
+
+	return auth.NewVerifier(auth.Config{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		ClockSkew:  cfg.ClockSkew,
+		Algorithms: cfg.Algorithms,
+		Keys:       keys,
+	})
+}
+
+// newAuthorizer builds the route authorizer from the configured policy and
+// role source
+func newAuthorizer(cfg *config.RBACConfig, log *logger.Logger) (*middleware.Authorizer, error) {
+	policy := rbac.DefaultPolicy()
+	if cfg.PolicyFile != "" {
+		var err error
+		if policy, err = rbac.LoadPolicy(cfg.PolicyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	source := middleware.RolesFromClaim(cfg.RoleClaim)
+	if cfg.RoleSource == "header" {
+		source = middleware.RolesFromHeader(cfg.RoleHeader)
+	}
+
+	return middleware.NewAuthorizer(policy, source, log), nil
 }
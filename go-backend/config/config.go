@@ -1,11 +1,19 @@
 package config
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"user-api/internal/ratelimit"
+	"user-api/internal/tracing"
 )
 
 type DBConfig struct {
@@ -28,6 +36,351 @@ func LoadDBConfig() *DBConfig {
 	}
 }
 
+// CursorConfig holds the settings for keyset pagination cursors
+type CursorConfig struct {
+	Secret []byte
+	// Generated is set when no secret was configured and a random one is used
+	Generated bool
+}
+
+// LoadCursorConfig loads the cursor signing secret. Without CURSOR_SECRET a
+// random secret is generated, so cursors only stay valid for the lifetime
+// of the process.
+func LoadCursorConfig() (*CursorConfig, error) {
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		return &CursorConfig{Secret: []byte(secret)}, nil
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate cursor secret: %w", err)
+	}
+	return &CursorConfig{Secret: secret, Generated: true}, nil
+}
+
+// ConcurrencyConfig holds the optimistic concurrency settings
+type ConcurrencyConfig struct {
+	// RequireIfMatch makes If-Match mandatory on PUT, PATCH and DELETE
+	RequireIfMatch bool
+}
+
+// LoadConcurrencyConfig loads optimistic concurrency settings
+func LoadConcurrencyConfig() *ConcurrencyConfig {
+	return &ConcurrencyConfig{
+		RequireIfMatch: getEnvBool("REQUIRE_IF_MATCH", false),
+	}
+}
+
+// RetentionConfig holds the soft delete retention settings
+type RetentionConfig struct {
+	// SoftDeleteRetention is how long deleted users can still be restored
+	SoftDeleteRetention time.Duration
+	// PurgeInterval is how often expired users are purged; zero disables purging
+	PurgeInterval time.Duration
+}
+
+// LoadRetentionConfig loads soft delete retention settings
+func LoadRetentionConfig() (*RetentionConfig, error) {
+	retention, err := getEnvDuration("SOFT_DELETE_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := getEnvDuration("PURGE_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RetentionConfig{
+		SoftDeleteRetention: retention,
+		PurgeInterval:       interval,
+	}, nil
+}
+
+// MigrationConfig holds the schema migration settings
+type MigrationConfig struct {
+	// RunOnStart applies pending migrations before the server starts
+	RunOnStart bool
+}
+
+// LoadMigrationConfig loads schema migration settings
+func LoadMigrationConfig() *MigrationConfig {
+	return &MigrationConfig{
+		RunOnStart: getEnvBool("MIGRATE_ON_START", false),
+	}
+}
+
+// AuthConfig holds the JWT bearer authentication settings
+type AuthConfig struct {
+	// Enabled requires a valid bearer token on every API request
+	Enabled   bool
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+	// Algorithms restricts the accepted signing algorithms; empty allows all
+	Algorithms []string
+	// HMACSecret verifies HS256 tokens
+	HMACSecret []byte
+	// JWKSFile and JWKSURL provide RS256 and EdDSA public keys
+	JWKSFile     string
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+}
+
+// LoadAuthConfig loads JWT authentication settings. Authentication is
+// disabled unless AUTH_ENABLED is set, and then needs at least one of
+// JWT_SECRET, JWT_JWKS_FILE or JWT_JWKS_URL.
+func LoadAuthConfig() (*AuthConfig, error) {
+	skew, err := getEnvDuration("JWT_CLOCK_SKEW", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := getEnvDuration("JWT_JWKS_CACHE_TTL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &AuthConfig{
+		Enabled:      getEnvBool("AUTH_ENABLED", false),
+		Issuer:       os.Getenv("JWT_ISSUER"),
+		Audience:     os.Getenv("JWT_AUDIENCE"),
+		ClockSkew:    skew,
+		HMACSecret:   []byte(os.Getenv("JWT_SECRET")),
+		JWKSFile:     os.Getenv("JWT_JWKS_FILE"),
+		JWKSURL:      os.Getenv("JWT_JWKS_URL"),
+		JWKSCacheTTL: ttl,
+	}
+	if algorithms := os.Getenv("JWT_ALGORITHMS"); algorithms != "" {
+		for _, alg := range strings.Split(algorithms, ",") {
+			cfg.Algorithms = append(cfg.Algorithms, strings.TrimSpace(alg))
+		}
+	}
+
+	if cfg.Enabled && len(cfg.HMACSecret) == 0 && cfg.JWKSFile == "" && cfg.JWKSURL == "" {
+		return nil, fmt.Errorf("AUTH_ENABLED requires JWT_SECRET, JWT_JWKS_FILE or JWT_JWKS_URL")
+	}
+
+	return cfg, nil
+}
+
+// RBACConfig holds the role-based access control settings
+type RBACConfig struct {
+	// Enabled enforces per-route permissions
+	Enabled bool
+	// RoleSource is "claim" to read roles from the verified token or
+	// "header" to trust a header set by a proxy
+	RoleSource string
+	RoleClaim  string
+	RoleHeader string
+	// PolicyFile replaces the default role to permission mapping
+	PolicyFile string
+}
+
+// LoadRBACConfig loads role-based access control settings
+func LoadRBACConfig() (*RBACConfig, error) {
+	cfg := &RBACConfig{
+		Enabled:    getEnvBool("RBAC_ENABLED", false),
+		RoleSource: getEnv("RBAC_ROLE_SOURCE", "claim"),
+		RoleClaim:  getEnv("RBAC_ROLE_CLAIM", "roles"),
+		RoleHeader: getEnv("RBAC_ROLE_HEADER", "X-User-Role"),
+		PolicyFile: os.Getenv("RBAC_POLICY_FILE"),
+	}
+	if cfg.RoleSource != "claim" && cfg.RoleSource != "header" {
+		return nil, fmt.Errorf("invalid RBAC_ROLE_SOURCE %q, use claim or header", cfg.RoleSource)
+	}
+	return cfg, nil
+}
+
+// RateLimitConfig holds per-client rate limits
+type RateLimitConfig struct {
+	// Enabled enforces rate limits on API routes
+	Enabled bool
+	// Default applies to routes without their own limit
+	Default ratelimit.Limit
+	// Routes maps route names such as "users.list" to their limit
+	Routes map[string]ratelimit.Limit
+}
+
+// LoadRateLimitConfig loads rate limits. RATE_LIMIT_ROUTES is a
+// comma-separated list of route=limit pairs, for example
+// "users.create=10/1m,users.list=60/1m", where each route is one of
+// routeNames.
+func LoadRateLimitConfig(routeNames []string) (*RateLimitConfig, error) {
+	cfg := &RateLimitConfig{
+		Enabled: getEnvBool("RATE_LIMIT_ENABLED", false),
+		Routes:  make(map[string]ratelimit.Limit),
+	}
+
+	var err error
+	if cfg.Default, err = ratelimit.ParseLimit(getEnv("RATE_LIMIT_DEFAULT", "120/1m")); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_DEFAULT: %w", err)
+	}
+
+	if value := os.Getenv("RATE_LIMIT_ROUTES"); value != "" {
+		for _, entry := range strings.Split(value, ",") {
+			route, limit, ok := strings.Cut(entry, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES entry %q, use route=limit", entry)
+			}
+			route = strings.TrimSpace(route)
+			if !slices.Contains(routeNames, route) {
+				return nil, fmt.Errorf("unknown route %q in RATE_LIMIT_ROUTES, use one of %s", route, strings.Join(routeNames, ", "))
+			}
+			parsed, err := ratelimit.ParseLimit(limit)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES: %w", err)
+			}
+			cfg.Routes[route] = parsed
+		}
+	}
+
+	return cfg, nil
+}
+
+// IdempotencyConfig holds Idempotency-Key settings
+type IdempotencyConfig struct {
+	// TTL is how long a stored response is replayed for its key
+	TTL time.Duration
+	// Lease is how long a request holds its key before a retry may take
+	// it over, in case the request died without completing
+	Lease time.Duration
+}
+
+// LoadIdempotencyConfig loads Idempotency-Key settings
+func LoadIdempotencyConfig() (*IdempotencyConfig, error) {
+	ttl, err := getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_TTL must be positive")
+	}
+	lease, err := getEnvDuration("IDEMPOTENCY_LEASE", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if lease <= 0 || lease > ttl {
+		return nil, fmt.Errorf("IDEMPOTENCY_LEASE must be positive and no longer than IDEMPOTENCY_TTL")
+	}
+	return &IdempotencyConfig{TTL: ttl, Lease: lease}, nil
+}
+
+// MetricsConfig holds Prometheus metrics settings
+type MetricsConfig struct {
+	// Enabled serves /metrics and records request and service metrics
+	Enabled bool
+}
+
+// LoadMetricsConfig loads Prometheus metrics settings
+func LoadMetricsConfig() *MetricsConfig {
+	return &MetricsConfig{
+		Enabled: getEnvBool("METRICS_ENABLED", true),
+	}
+}
+
+// TracingConfig holds OpenTelemetry tracing settings
+type TracingConfig struct {
+	// Exporter is none, stdout, file or otlp
+	Exporter string
+	// File receives spans with the file exporter
+	File        string
+	ServiceName string
+	// SampleRatio is the fraction of new traces that are recorded
+	SampleRatio float64
+}
+
+// LoadTracingConfig loads tracing settings. The OTLP exporter is configured
+// with the standard OTEL_EXPORTER_OTLP_* variables.
+func LoadTracingConfig() (*TracingConfig, error) {
+	cfg := &TracingConfig{
+		Exporter:    getEnv("TRACING_EXPORTER", "none"),
+		File:        getEnv("TRACING_FILE", "traces.jsonl"),
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "user-api"),
+		SampleRatio: 1,
+	}
+	if value := os.Getenv("TRACING_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid TRACING_SAMPLE_RATIO %q, use a number from 0 to 1", value)
+		}
+		cfg.SampleRatio = ratio
+	}
+	return cfg, nil
+}
+
+// HealthConfig holds health probe settings
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check
+	CheckTimeout time.Duration
+}
+
+// LoadHealthConfig loads health probe settings
+func LoadHealthConfig() (*HealthConfig, error) {
+	timeout, err := getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("HEALTH_CHECK_TIMEOUT must be positive")
+	}
+	return &HealthConfig{CheckTimeout: timeout}, nil
+}
+
+// AgeConfig holds age calculation settings
+type AgeConfig struct {
+	// Location is the time zone whose current date ages are calculated on
+	// when a request does not name one with tz
+	Location *time.Location
+}
+
+// LoadAgeConfig loads age calculation settings
+func LoadAgeConfig() (*AgeConfig, error) {
+	name := getEnv("AGE_TIMEZONE", "UTC")
+	location, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("AGE_TIMEZONE must be an IANA time zone, got %q", name)
+	}
+	return &AgeConfig{Location: location}, nil
+}
+
+// ValidationConfig holds validation message settings
+type ValidationConfig struct {
+	// TranslationsDir holds <locale>.json files that override or add to the
+	// built-in translations. Empty uses the built-in ones only
+	TranslationsDir string
+}
+
+// LoadValidationConfig loads validation message settings
+func LoadValidationConfig() *ValidationConfig {
+	return &ValidationConfig{
+		TranslationsDir: getEnv("VALIDATION_TRANSLATIONS_DIR", ""),
+	}
+}
+
+// ShutdownConfig holds graceful shutdown settings
+type ShutdownConfig struct {
+	// DrainDelay is how long /readyz reports draining before the server
+	// stops accepting requests, giving load balancers time to notice
+	DrainDelay time.Duration
+	// Timeout bounds how long in-flight requests may run after that
+	Timeout time.Duration
+}
+
+// LoadShutdownConfig loads graceful shutdown settings
+func LoadShutdownConfig() (*ShutdownConfig, error) {
+	drainDelay, err := getEnvDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if drainDelay < 0 || timeout <= 0 {
+		return nil, fmt.Errorf("SHUTDOWN_DRAIN_DELAY must not be negative and SHUTDOWN_TIMEOUT must be positive")
+	}
+	return &ShutdownConfig{DrainDelay: drainDelay, Timeout: timeout}, nil
+}
+
 func NewDBConnection() (*sql.DB, error) {
 	cfg := LoadDBConfig()
 
@@ -36,10 +389,12 @@ func NewDBConnection() (*sql.DB, error) {
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
 	)
 
-	db, err := sql.Open("postgres", dsn)
+	// Every query is traced as a child of the span in its context
+	connector, err := pq.NewConnector(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db := sql.OpenDB(tracing.WrapConnector(connector))
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
@@ -58,3 +413,22 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
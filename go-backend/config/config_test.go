@@ -0,0 +1,28 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadRateLimitConfig_Routes(t *testing.T) {
+	names := []string{"users.create", "users.list"}
+
+	t.Setenv("RATE_LIMIT_ROUTES", "users.create=10/1m, users.list=off")
+	cfg, err := LoadRateLimitConfig(names)
+	if err != nil {
+		t.Fatalf("LoadRateLimitConfig() error = %v", err)
+	}
+	if got := cfg.Routes["users.create"].String(); got != "10/1m0s" {
+		t.Errorf("users.create limit = %s, expected 10/1m0s", got)
+	}
+	if !cfg.Routes["users.list"].Unlimited() {
+		t.Errorf("users.list limit = %s, expected off", cfg.Routes["users.list"])
+	}
+
+	// A misspelt route would otherwise silently fall back to the default
+	t.Setenv("RATE_LIMIT_ROUTES", "users.create=10/1m,user.list=60/1m")
+	if _, err := LoadRateLimitConfig(names); err == nil || !strings.Contains(err.Error(), `"user.list"`) {
+		t.Errorf("LoadRateLimitConfig() error = %v, expected unknown route user.list", err)
+	}
+}
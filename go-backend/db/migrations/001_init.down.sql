@@ -0,0 +1,4 @@
+-- Drop users table together with its trigger and indexes
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -0,0 +1,4 @@
+-- Remove soft delete support from users
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+-- Drop the name search indexes; pg_trgm is kept as other objects may use it
+DROP INDEX IF EXISTS idx_users_name_trgm;
+DROP INDEX IF EXISTS idx_users_name_pattern;
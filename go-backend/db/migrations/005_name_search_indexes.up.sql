@@ -0,0 +1,9 @@
+-- Index name searches. idx_users_name follows the database collation so it
+-- can serve ORDER BY name, but unless that collation is C it cannot serve
+-- name LIKE 'x%'; text_pattern_ops compares bytes and can.
+CREATE INDEX IF NOT EXISTS idx_users_name_pattern ON users(name text_pattern_ops);
+
+-- Trigram index for name ILIKE '%x%'. pg_trgm is a trusted extension, so
+-- the database owner can create it.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (name gin_trgm_ops);
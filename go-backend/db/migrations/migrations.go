@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL schema migrations into the binary.
+// Files are named <version>_<name>.up.sql with an optional matching
+// <version>_<name>.down.sql that reverts them.
+package migrations
+
+import "embed"
+
+// FS holds every migration file
+//
+//go:embed *.sql
+var FS embed.FS
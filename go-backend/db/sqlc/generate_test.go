@@ -0,0 +1,179 @@
+package sqlc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// These tests fail when the generated code no longer matches query.sql.
+// Regenerate with `sqlc generate` in this directory after editing queries.
+// TestSqlcDiff runs sqlc itself and checks every generated file; the other
+// tests check the query text and Querier methods without it.
+
+var (
+	queryHeader = regexp.MustCompile(`(?m)^-- name: (\w+) (:\w+)\s*$`)
+	namedParam  = regexp.MustCompile(`sqlc\.n?arg\(\s*'?(\w+)'?\s*\)|@(\w+)`)
+)
+
+// parseQueries reads query.sql and returns each query as sqlc embeds it in
+// the generated code, keyed by name
+func parseQueries(t *testing.T) map[string]string {
+	t.Helper()
+
+	content, err := os.ReadFile("query.sql")
+	if err != nil {
+		t.Fatalf("Failed to read query.sql: %v", err)
+	}
+	source := string(content)
+
+	queries := make(map[string]string)
+	headers := queryHeader.FindAllStringSubmatchIndex(source, -1)
+	for i, h := range headers {
+		end := len(source)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		name := source[h[2]:h[3]]
+
+		text := strings.TrimSpace(source[h[0]:end])
+		text = strings.TrimSuffix(text, ";")
+		queries[name] = numberParams(text) + "\n"
+	}
+
+	return queries
+}
+
+// numberParams rewrites named parameters into positional ones, numbered by
+// first appearance, the same way sqlc does
+func numberParams(query string) string {
+	positions := make(map[string]int)
+	return namedParam.ReplaceAllStringFunc(query, func(match string) string {
+		groups := namedParam.FindStringSubmatch(match)
+		name := groups[1] + groups[2]
+		if _, ok := positions[name]; !ok {
+			positions[name] = len(positions) + 1
+		}
+		return "$" + strconv.Itoa(positions[name])
+	})
+}
+
+// parseGenerated returns the embedded query constants of query.sql.go keyed
+// by query name
+func parseGenerated(t *testing.T) map[string]string {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "query.sql.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse query.sql.go: %v", err)
+	}
+
+	queries := make(map[string]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatalf("Failed to unquote %s: %v", lit.Value, err)
+		}
+		if match := queryHeader.FindStringSubmatch(strings.SplitN(value, "\n", 2)[0]); match != nil {
+			queries[match[1]] = value
+		}
+		return true
+	})
+
+	return queries
+}
+
+// querierMethods returns the method names of the generated Querier interface
+func querierMethods(t *testing.T) []string {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "querier.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse querier.go: %v", err)
+	}
+
+	var methods []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != "Querier" {
+			return true
+		}
+		for _, field := range spec.Type.(*ast.InterfaceType).Methods.List {
+			for _, name := range field.Names {
+				methods = append(methods, name.Name)
+			}
+		}
+		return false
+	})
+
+	sort.Strings(methods)
+	return methods
+}
+
+func TestGeneratedQueriesMatchQuerySQL(t *testing.T) {
+	expected := parseQueries(t)
+	generated := parseGenerated(t)
+
+	if len(expected) == 0 {
+		t.Fatal("No queries found in query.sql")
+	}
+
+	for name, query := range expected {
+		got, ok := generated[name]
+		if !ok {
+			t.Errorf("Query %s is missing from query.sql.go", name)
+			continue
+		}
+		if got != query {
+			t.Errorf("Query %s differs from query.sql\ngenerated:\n%s\nquery.sql:\n%s", name, got, query)
+		}
+	}
+
+	for name := range generated {
+		if _, ok := expected[name]; !ok {
+			t.Errorf("Query %s in query.sql.go no longer exists in query.sql", name)
+		}
+	}
+}
+
+func TestQuerierMatchesQuerySQL(t *testing.T) {
+	var expected []string
+	for name := range parseQueries(t) {
+		expected = append(expected, name)
+	}
+	sort.Strings(expected)
+
+	methods := querierMethods(t)
+	if strings.Join(methods, ",") != strings.Join(expected, ",") {
+		t.Errorf("Querier methods = %v, expected %v", methods, expected)
+	}
+}
+
+// TestSqlcDiff fails when `sqlc generate` would change the generated code,
+// including Go types, params structs and array wrapping. It is skipped when
+// sqlc is not installed, unless SQLC_REQUIRED is set as it should be in CI.
+func TestSqlcDiff(t *testing.T) {
+	path, err := exec.LookPath("sqlc")
+	if err != nil {
+		if os.Getenv("SQLC_REQUIRED") != "" {
+			t.Fatal("sqlc is not installed but SQLC_REQUIRED is set")
+		}
+		t.Skip("sqlc is not installed; install it to check the generated code")
+	}
+
+	out, err := exec.Command(path, "diff").CombinedOutput()
+	if err != nil {
+		t.Errorf("sqlc diff found generated code out of date with query.sql; run `sqlc generate`:\n%s", out)
+	}
+}
@@ -1,33 +1,155 @@
 -- name: GetUser :one
-SELECT id, name, dob, created_at, updated_at
+SELECT id, name, dob, created_at, updated_at, deleted_at
 FROM users
-WHERE id = $1;
+WHERE id = @id AND (@include_deleted::boolean OR deleted_at IS NULL);
 
--- name: ListUsers :many
-SELECT id, name, dob, created_at, updated_at
-FROM users
-ORDER BY id
-LIMIT $1 OFFSET $2;
-
--- name: ListAllUsers :many
-SELECT id, name, dob, created_at, updated_at
-FROM users
-ORDER BY id;
+-- name: UserExists :one
+SELECT EXISTS (
+    SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL
+);
 
 -- name: CreateUser :one
 INSERT INTO users (name, dob)
 VALUES ($1, $2)
-RETURNING id, name, dob, created_at, updated_at;
+RETURNING id, name, dob, created_at, updated_at, deleted_at;
+
+-- name: CreateUsers :many
+WITH input AS (
+    SELECT nextval(pg_get_serial_sequence('users', 'id')) AS id, n.name, d.dob, n.ord
+    FROM unnest(@names::text[]) WITH ORDINALITY AS n(name, ord)
+    JOIN unnest(@dobs::date[]) WITH ORDINALITY AS d(dob, ord) USING (ord)
+), inserted AS (
+    INSERT INTO users (id, name, dob)
+    SELECT id, name, dob FROM input
+    RETURNING id
+)
+SELECT input.ord::bigint AS ord, inserted.id::bigint AS id
+FROM inserted
+JOIN input USING (id)
+ORDER BY input.ord;
 
 -- name: UpdateUser :one
 UPDATE users
-SET name = $2, dob = $3
-WHERE id = $1
-RETURNING id, name, dob, created_at, updated_at;
+SET name = @name, dob = @dob
+WHERE id = @id AND deleted_at IS NULL
+    AND (sqlc.narg('version')::timestamptz IS NULL OR updated_at = sqlc.narg('version'))
+RETURNING id, name, dob, created_at, updated_at, deleted_at;
+
+-- name: PatchUser :one
+UPDATE users
+SET name = COALESCE(sqlc.narg('name')::text, name),
+    dob = COALESCE(sqlc.narg('dob')::date, dob)
+WHERE id = @id AND deleted_at IS NULL
+    AND (sqlc.narg('version')::timestamptz IS NULL OR updated_at = sqlc.narg('version'))
+RETURNING id, name, dob, created_at, updated_at, deleted_at;
+
+-- name: DeleteUser :execrows
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = @id AND deleted_at IS NULL
+    AND (sqlc.narg('version')::timestamptz IS NULL OR updated_at = sqlc.narg('version'));
 
--- name: DeleteUser :exec
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL
+WHERE id = @id AND deleted_at IS NOT NULL
+    AND (sqlc.narg('version')::timestamptz IS NULL OR updated_at = sqlc.narg('version'))
+RETURNING id, name, dob, created_at, updated_at, deleted_at;
+
+-- name: PurgeDeletedUsers :execrows
 DELETE FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < @deleted_before;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (name, prefix, salt, hash, scopes)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, prefix, salt, hash, scopes, created_at, last_used_at, revoked_at;
+
+-- name: GetAPIKey :one
+SELECT id, name, prefix, salt, hash, scopes, created_at, last_used_at, revoked_at
+FROM api_keys
 WHERE id = $1;
 
--- name: CountUsers :one
-SELECT COUNT(*) FROM users;
+-- name: GetAPIKeyByPrefix :one
+SELECT id, name, prefix, salt, hash, scopes, created_at, last_used_at, revoked_at
+FROM api_keys
+WHERE prefix = $1;
+
+-- name: ListAPIKeys :many
+SELECT id, name, prefix, salt, hash, scopes, created_at, last_used_at, revoked_at
+FROM api_keys
+ORDER BY id;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING id, name, prefix, salt, hash, scopes, created_at, last_used_at, revoked_at;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute');
+
+-- name: ClaimIdempotencyKey :one
+INSERT INTO idempotency_keys (scope, key, fingerprint, expires_at)
+VALUES (@scope, @key, @fingerprint, @expires_at)
+ON CONFLICT (scope, key) DO UPDATE
+SET fingerprint = EXCLUDED.fingerprint,
+    status_code = NULL,
+    headers = '{}',
+    body = NULL,
+    created_at = CURRENT_TIMESTAMP,
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+    OR (idempotency_keys.status_code IS NULL
+        AND idempotency_keys.created_at <= CURRENT_TIMESTAMP - sqlc.arg(lease_seconds)::float8 * INTERVAL '1 second')
+RETURNING scope, key, fingerprint, status_code, headers, body, created_at, expires_at;
+
+-- name: GetIdempotencyKey :one
+SELECT scope, key, fingerprint, status_code, headers, body, created_at, expires_at
+FROM idempotency_keys
+WHERE scope = @scope AND key = @key;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status_code = @status_code, headers = @headers, body = @body
+WHERE scope = @scope AND key = @key;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE scope = @scope AND key = @key;
+
+-- name: PurgeExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at <= CURRENT_TIMESTAMP;
+
+-- name: ListBirthdays :many
+SELECT users.id, users.name, users.dob, users.created_at, users.updated_at, users.deleted_at, b.birthday
+FROM users
+CROSS JOIN LATERAL (
+    SELECT (users.dob + (sqlc.arg(year)::int - date_part('year', users.dob)::int) * INTERVAL '1 year')::date AS this_year
+) t
+CROSS JOIN LATERAL (
+    SELECT (CASE
+        WHEN t.this_year >= @from_date::date AND t.this_year > users.dob THEN t.this_year
+        ELSE users.dob + (sqlc.arg(year)::int + 1 - date_part('year', users.dob)::int) * INTERVAL '1 year'
+    END)::date AS birthday
+) b
+WHERE users.deleted_at IS NULL AND b.birthday <= @to_date::date AND b.birthday > users.dob
+ORDER BY b.birthday, users.id
+OFFSET @row_offset LIMIT @row_limit;
+
+-- name: CountBirthdays :one
+SELECT COUNT(*)
+FROM users
+CROSS JOIN LATERAL (
+    SELECT (users.dob + (sqlc.arg(year)::int - date_part('year', users.dob)::int) * INTERVAL '1 year')::date AS this_year
+) t
+CROSS JOIN LATERAL (
+    SELECT (CASE
+        WHEN t.this_year >= @from_date::date AND t.this_year > users.dob THEN t.this_year
+        ELSE users.dob + (sqlc.arg(year)::int + 1 - date_part('year', users.dob)::int) * INTERVAL '1 year'
+    END)::date AS birthday
+) b
+WHERE users.deleted_at IS NULL AND b.birthday <= @to_date::date AND b.birthday > users.dob;
@@ -0,0 +1,16 @@
+// Package docs embeds the OpenAPI document describing the HTTP API and the
+// page that renders it. openapi.json is maintained by hand; the routes tests
+// fail when it no longer matches the registered routes.
+package docs
+
+import _ "embed"
+
+// OpenAPI holds the OpenAPI 3.1 document
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// UI holds the HTML page that renders OpenAPI with Swagger UI
+//
+//go:embed index.html
+var UI []byte
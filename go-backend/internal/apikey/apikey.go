@@ -0,0 +1,76 @@
+// Package apikey generates API keys and verifies them against salted hashes.
+//
+// A key looks like uak_<prefix>_<secret>. The prefix is stored in clear to
+// find the key record; only a salted SHA-256 hash of the secret is stored.
+// Secrets are 256 random bits, so a fast hash is sufficient.
+package apikey
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// keyPrefix marks a string as an API key of this service
+const keyPrefix = "uak"
+
+const (
+	prefixBytes = 6
+	secretBytes = 32
+	saltBytes   = 16
+)
+
+var ErrMalformedKey = errors.New("malformed API key")
+
+// Key is a freshly generated API key. Plaintext is only ever shown once.
+type Key struct {
+	Plaintext string
+	Prefix    string
+	Salt      []byte
+	Hash      []byte
+}
+
+// Generate creates a new random key with its salted hash
+func Generate() (*Key, error) {
+	buf := make([]byte, prefixBytes+secretBytes+saltBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	prefix := hex.EncodeToString(buf[:prefixBytes])
+	secret := base64.RawURLEncoding.EncodeToString(buf[prefixBytes : prefixBytes+secretBytes])
+	salt := buf[prefixBytes+secretBytes:]
+
+	return &Key{
+		Plaintext: keyPrefix + "_" + prefix + "_" + secret,
+		Prefix:    prefix,
+		Salt:      salt,
+		Hash:      Hash(salt, secret),
+	}, nil
+}
+
+// Parse splits a presented key into its lookup prefix and secret
+func Parse(raw string) (prefix, secret string, err error) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || len(parts[1]) != 2*prefixBytes || parts[2] == "" {
+		return "", "", ErrMalformedKey
+	}
+	return parts[1], parts[2], nil
+}
+
+// Hash computes the salted hash stored for a secret
+func Hash(salt []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(secret))
+	return mac.Sum(nil)
+}
+
+// Verify reports whether secret matches the stored salted hash, in
+// constant time
+func Verify(salt, hash []byte, secret string) bool {
+	return hmac.Equal(Hash(salt, secret), hash)
+}
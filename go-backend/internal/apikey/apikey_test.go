@@ -0,0 +1,47 @@
+package apikey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAndVerify(t *testing.T) {
+	key, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.HasPrefix(key.Plaintext, "uak_"+key.Prefix+"_") {
+		t.Errorf("Plaintext %q does not start with the prefix %q", key.Plaintext, key.Prefix)
+	}
+
+	prefix, secret, err := Parse(key.Plaintext)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if prefix != key.Prefix {
+		t.Errorf("Parse() prefix = %q, expected %q", prefix, key.Prefix)
+	}
+	if !Verify(key.Salt, key.Hash, secret) {
+		t.Error("Verify() rejected the generated secret")
+	}
+	if Verify(key.Salt, key.Hash, secret+"x") {
+		t.Error("Verify() accepted a wrong secret")
+	}
+
+	other, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if other.Prefix == key.Prefix || string(other.Salt) == string(key.Salt) {
+		t.Error("Generate() returned the same prefix or salt twice")
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	for _, raw := range []string{"", "uak", "uak_abc_secret", "xyz_0123456789ab_secret", "uak_0123456789ab_"} {
+		if _, _, err := Parse(raw); !errors.Is(err, ErrMalformedKey) {
+			t.Errorf("Parse(%q) error = %v, expected ErrMalformedKey", raw, err)
+		}
+	}
+}
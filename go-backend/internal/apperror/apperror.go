@@ -0,0 +1,232 @@
+// Package apperror defines the application error model and the catalogue of
+// stable error codes. Errors are rendered as RFC 7807 problem details with
+// the application/problem+json media type; docs/errors.md documents every
+// code.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ContentType is the media type of error responses
+const ContentType = "application/problem+json"
+
+// TypePrefix prefixes the code to form the problem type URI
+const TypePrefix = "urn:user-api:problem:"
+
+// Code identifies a kind of error. Codes are part of the API contract and
+// never change meaning once published.
+type Code string
+
+const (
+	CodeInvalidRequest        Code = "invalid_request"
+	CodeValidationFailed      Code = "validation_failed"
+	CodeInvalidQuery          Code = "invalid_query"
+	CodeUnauthorized          Code = "unauthorized"
+	CodeForbidden             Code = "forbidden"
+	CodeNotFound              Code = "not_found"
+	CodeUserNotFound          Code = "user_not_found"
+	CodeAPIKeyNotFound        Code = "api_key_not_found"
+	CodeMethodNotAllowed      Code = "method_not_allowed"
+	CodeNotAcceptable         Code = "not_acceptable"
+	CodeUserNotDeleted        Code = "user_not_deleted"
+	CodeAPIKeyRevoked         Code = "api_key_revoked"
+	CodePatchTestFailed       Code = "patch_test_failed"
+	CodeIdempotencyInProgress Code = "idempotency_in_progress"
+	CodePreconditionFailed    Code = "precondition_failed"
+	CodePayloadTooLarge       Code = "payload_too_large"
+	CodeUnsupportedMediaType  Code = "unsupported_media_type"
+	CodeIdempotencyKeyReused  Code = "idempotency_key_reused"
+	CodeImportRejected        Code = "import_rejected"
+	CodePreconditionRequired  Code = "precondition_required"
+	CodeRateLimited           Code = "rate_limited"
+	CodeInternal              Code = "internal_error"
+)
+
+// Entry is the catalogue entry of a code
+type Entry struct {
+	Code   Code
+	Status int
+	Title  string
+}
+
+var catalogue = map[Code]Entry{
+	CodeInvalidRequest:        {CodeInvalidRequest, fiber.StatusBadRequest, "Invalid request"},
+	CodeValidationFailed:      {CodeValidationFailed, fiber.StatusBadRequest, "Validation failed"},
+	CodeInvalidQuery:          {CodeInvalidQuery, fiber.StatusBadRequest, "Invalid query parameter"},
+	CodeUnauthorized:          {CodeUnauthorized, fiber.StatusUnauthorized, "Authentication required"},
+	CodeForbidden:             {CodeForbidden, fiber.StatusForbidden, "Forbidden"},
+	CodeNotFound:              {CodeNotFound, fiber.StatusNotFound, "Not found"},
+	CodeUserNotFound:          {CodeUserNotFound, fiber.StatusNotFound, "User not found"},
+	CodeAPIKeyNotFound:        {CodeAPIKeyNotFound, fiber.StatusNotFound, "API key not found"},
+	CodeMethodNotAllowed:      {CodeMethodNotAllowed, fiber.StatusMethodNotAllowed, "Method not allowed"},
+	CodeNotAcceptable:         {CodeNotAcceptable, fiber.StatusNotAcceptable, "Not acceptable"},
+	CodeUserNotDeleted:        {CodeUserNotDeleted, fiber.StatusConflict, "User is not deleted"},
+	CodeAPIKeyRevoked:         {CodeAPIKeyRevoked, fiber.StatusConflict, "API key is revoked"},
+	CodePatchTestFailed:       {CodePatchTestFailed, fiber.StatusConflict, "Patch test failed"},
+	CodeIdempotencyInProgress: {CodeIdempotencyInProgress, fiber.StatusConflict, "Request in progress"},
+	CodePreconditionFailed:    {CodePreconditionFailed, fiber.StatusPreconditionFailed, "Precondition failed"},
+	CodePayloadTooLarge:       {CodePayloadTooLarge, fiber.StatusRequestEntityTooLarge, "Payload too large"},
+	CodeUnsupportedMediaType:  {CodeUnsupportedMediaType, fiber.StatusUnsupportedMediaType, "Unsupported media type"},
+	CodeIdempotencyKeyReused:  {CodeIdempotencyKeyReused, fiber.StatusUnprocessableEntity, "Idempotency key reused"},
+	CodeImportRejected:        {CodeImportRejected, fiber.StatusUnprocessableEntity, "Import rejected"},
+	CodePreconditionRequired:  {CodePreconditionRequired, fiber.StatusPreconditionRequired, "Precondition required"},
+	CodeRateLimited:           {CodeRateLimited, fiber.StatusTooManyRequests, "Too many requests"},
+	CodeInternal:              {CodeInternal, fiber.StatusInternalServerError, "Internal server error"},
+}
+
+// statusCodes maps the statuses of errors raised by Fiber itself, such as
+// unmatched routes, to codes
+var statusCodes = map[int]Code{
+	fiber.StatusBadRequest:            CodeInvalidRequest,
+	fiber.StatusNotFound:              CodeNotFound,
+	fiber.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	fiber.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	fiber.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+}
+
+// Catalogue returns every code sorted by name
+func Catalogue() []Entry {
+	entries := make([]Entry, 0, len(catalogue))
+	for _, entry := range catalogue {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Code < entries[j].Code
+	})
+	return entries
+}
+
+// InvalidParam describes why one field or parameter was rejected
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Error is an application error. Code, Status and Title come from the
+// catalogue; Detail explains this occurrence to the client. Err is the
+// underlying cause, which is logged but never rendered.
+type Error struct {
+	Code          Code
+	Status        int
+	Title         string
+	Detail        string
+	InvalidParams []InvalidParam
+	// Extensions are additional members of the problem document
+	Extensions map[string]any
+	Err        error
+}
+
+// New creates an error with the catalogue status and title of code
+func New(code Code, detail string) *Error {
+	entry, ok := catalogue[code]
+	if !ok {
+		entry = catalogue[CodeInternal]
+	}
+	return &Error{
+		Code:   entry.Code,
+		Status: entry.Status,
+		Title:  entry.Title,
+		Detail: detail,
+	}
+}
+
+// Wrap creates an error like New that records err as its cause
+func Wrap(code Code, detail string, err error) *Error {
+	e := New(code, detail)
+	e.Err = err
+	return e
+}
+
+// WithParams adds one invalid parameter per field, sorted by field name
+func (e *Error) WithParams(fields map[string]string) *Error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e.InvalidParams = append(e.InvalidParams, InvalidParam{Name: name, Reason: fields[name]})
+	}
+	return e
+}
+
+// With adds an extension member to the problem document
+func (e *Error) With(key string, value any) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]any)
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := string(e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// From converts any error to an *Error. Errors raised by Fiber keep their
+// status; any other error becomes an internal error whose message is not
+// exposed.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		if code, ok := statusCodes[fiberErr.Code]; ok {
+			return New(code, fiberErr.Message)
+		}
+		if fiberErr.Code < fiber.StatusInternalServerError {
+			e := New(CodeInvalidRequest, fiberErr.Message)
+			e.Status = fiberErr.Code
+			e.Title = http.StatusText(fiberErr.Code)
+			return e
+		}
+	}
+
+	return Wrap(CodeInternal, "", err)
+}
+
+// Respond writes err as a problem document. The request ID is taken from
+// the X-Request-ID response header set by the RequestID middleware.
+func Respond(c *fiber.Ctx, err error) error {
+	e := From(err)
+
+	body := make(map[string]any, len(e.Extensions)+8)
+	for key, value := range e.Extensions {
+		body[key] = value
+	}
+	body["type"] = TypePrefix + string(e.Code)
+	body["title"] = e.Title
+	body["status"] = e.Status
+	body["code"] = e.Code
+	body["instance"] = c.Path()
+	if e.Detail != "" {
+		body["detail"] = e.Detail
+	}
+	if len(e.InvalidParams) > 0 {
+		body["invalid_params"] = e.InvalidParams
+	}
+	if requestID := c.GetRespHeader(fiber.HeaderXRequestID); requestID != "" {
+		body["request_id"] = requestID
+	}
+
+	return c.Status(e.Status).JSON(body, ContentType)
+}
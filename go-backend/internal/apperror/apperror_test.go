@@ -0,0 +1,96 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRespond(t *testing.T) {
+	app := fiber.New()
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderXRequestID, "req-1")
+		return Respond(c, New(CodeValidationFailed, "One or more fields are invalid").
+			WithParams(map[string]string{"name": "name is required", "dob": "dob is invalid"}).
+			With("hint", "see docs"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/users/1", nil))
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, expected %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentType); got != ContentType {
+		t.Errorf("Content-Type = %q, expected %q", got, ContentType)
+	}
+
+	var body struct {
+		Type          string         `json:"type"`
+		Title         string         `json:"title"`
+		Status        int            `json:"status"`
+		Detail        string         `json:"detail"`
+		Instance      string         `json:"instance"`
+		Code          Code           `json:"code"`
+		RequestID     string         `json:"request_id"`
+		InvalidParams []InvalidParam `json:"invalid_params"`
+		Hint          string         `json:"hint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if body.Type != TypePrefix+"validation_failed" || body.Title != "Validation failed" || body.Status != fiber.StatusBadRequest ||
+		body.Code != CodeValidationFailed || body.Instance != "/users/1" || body.RequestID != "req-1" || body.Hint != "see docs" {
+		t.Errorf("body = %+v", body)
+	}
+	if len(body.InvalidParams) != 2 || body.InvalidParams[0].Name != "dob" || body.InvalidParams[1].Name != "name" {
+		t.Errorf("invalid_params = %+v, expected dob then name", body.InvalidParams)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   Code
+		wantStatus int
+		wantDetail string
+	}{
+		{"app error", Wrap(CodeUserNotFound, "gone", errors.New("sql: no rows")), CodeUserNotFound, fiber.StatusNotFound, "gone"},
+		{"unmatched route", fiber.ErrNotFound, CodeNotFound, fiber.StatusNotFound, "Not Found"},
+		{"wrong method", fiber.ErrMethodNotAllowed, CodeMethodNotAllowed, fiber.StatusMethodNotAllowed, "Method Not Allowed"},
+		{"other client error", fiber.ErrRequestTimeout, CodeInvalidRequest, fiber.StatusRequestTimeout, "Request Timeout"},
+		{"internal", errors.New("connection refused"), CodeInternal, fiber.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if got.Code != tt.wantCode || got.Status != tt.wantStatus || got.Detail != tt.wantDetail {
+				t.Errorf("From() = %s %d %q, expected %s %d %q", got.Code, got.Status, got.Detail, tt.wantCode, tt.wantStatus, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestCatalogue_Documented(t *testing.T) {
+	doc, err := os.ReadFile("../../docs/errors.md")
+	if err != nil {
+		t.Fatalf("Failed to read errors.md: %v", err)
+	}
+
+	// Every code needs a row starting with its code and status
+	for _, entry := range Catalogue() {
+		row := fmt.Sprintf("| `%s` | %d |", entry.Code, entry.Status)
+		if !strings.Contains(string(doc), row) {
+			t.Errorf("docs/errors.md has no row %q", row)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package auth verifies JWT bearer tokens signed with HS256, RS256 or EdDSA.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrMissingToken = errors.New("missing bearer token")
+)
+
+// SupportedAlgorithms lists the signing algorithms a Verifier accepts
+var SupportedAlgorithms = []string{"HS256", "RS256", "EdDSA"}
+
+// Config holds the token validation rules
+type Config struct {
+	// Issuer, when set, must equal the iss claim
+	Issuer string
+	// Audience, when set, must be listed in the aud claim
+	Audience string
+	// ClockSkew is the leeway applied to exp, nbf and iat
+	ClockSkew time.Duration
+	// Algorithms restricts the accepted algorithms; empty means all supported
+	Algorithms []string
+	// Keys are consulted in order for the verification key
+	Keys []KeySet
+}
+
+// Identity is the verified caller of a request
+type Identity struct {
+	Subject string
+	Claims  jwt.MapClaims
+}
+
+// Verifier validates bearer tokens
+type Verifier struct {
+	config Config
+	now    func() time.Time
+}
+
+// NewVerifier creates a Verifier, rejecting unsupported algorithms and
+// configurations without keys
+func NewVerifier(config Config) (*Verifier, error) {
+	if len(config.Keys) == 0 {
+		return nil, errors.New("auth: no verification keys configured")
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = SupportedAlgorithms
+	}
+	for _, alg := range config.Algorithms {
+		if !supported(alg) {
+			return nil, fmt.Errorf("auth: unsupported algorithm %q", alg)
+		}
+	}
+
+	return &Verifier{config: config, now: time.Now}, nil
+}
+
+// Verify parses and validates a token and returns the caller identity.
+// Every failure is reported as ErrInvalidToken wrapping the cause.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(v.config.Algorithms),
+		jwt.WithLeeway(v.config.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(v.now),
+	}
+	if v.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(v.config.Issuer))
+	}
+	if v.config.Audience != "" {
+		options = append(options, jwt.WithAudience(v.config.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid, t.Method.Alg())
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	return &Identity{Subject: subject, Claims: claims}, nil
+}
+
+// key returns the first key found across the configured key sets
+func (v *Verifier) key(ctx context.Context, kid, alg string) (interface{}, error) {
+	err := ErrUnknownKey
+	for _, keys := range v.config.Keys {
+		var value interface{}
+		value, err = keys.Key(ctx, kid, alg)
+		if err == nil {
+			return value, nil
+		}
+	}
+	return nil, err
+}
+
+func supported(alg string) bool {
+	for _, a := range SupportedAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testNow = time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func claims(overrides jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub": "user-42",
+		"iss": "https://issuer.example",
+		"aud": "user-api",
+		"iat": testNow.Add(-time.Minute).Unix(),
+		"exp": testNow.Add(time.Hour).Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, c jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func newTestVerifier(t *testing.T, keys ...KeySet) *Verifier {
+	t.Helper()
+	v, err := NewVerifier(Config{
+		Issuer:    "https://issuer.example",
+		Audience:  "user-api",
+		ClockSkew: 30 * time.Second,
+		Keys:      keys,
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestVerify_HS256(t *testing.T) {
+	secret := []byte("test-secret")
+	v := newTestVerifier(t, NewSecretKeySet(secret))
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{"valid", claims(nil), false},
+		{"expired within skew", claims(jwt.MapClaims{"exp": testNow.Add(-10 * time.Second).Unix()}), false},
+		{"expired beyond skew", claims(jwt.MapClaims{"exp": testNow.Add(-time.Minute).Unix()}), true},
+		{"missing exp", claims(jwt.MapClaims{"exp": nil}), true},
+		{"not yet valid", claims(jwt.MapClaims{"nbf": testNow.Add(time.Minute).Unix()}), true},
+		{"wrong issuer", claims(jwt.MapClaims{"iss": "https://other.example"}), true},
+		{"wrong audience", claims(jwt.MapClaims{"aud": "other-api"}), true},
+		{"audience list", claims(jwt.MapClaims{"aud": []string{"other-api", "user-api"}}), false},
+		{"missing subject", claims(jwt.MapClaims{"sub": nil}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, secret, "", tt.claims))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify() error = %v, expected ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if identity.Subject != "user-42" || identity.Claims["iss"] != "https://issuer.example" {
+				t.Errorf("Verify() identity = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestVerify_RejectsWrongKeyAndAlgorithm(t *testing.T) {
+	v := newTestVerifier(t, NewSecretKeySet([]byte("test-secret")))
+
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("other"), "", claims(nil))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with wrong secret error = %v, expected ErrInvalidToken", err)
+	}
+
+	unsigned := sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil))
+	if _, err := v.Verify(context.Background(), unsigned); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with alg none error = %v, expected ErrInvalidToken", err)
+	}
+
+	hs384 := sign(t, jwt.SigningMethodHS384, []byte("test-secret"), "", claims(nil))
+	if _, err := v.Verify(context.Background(), hs384); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with HS384 error = %v, expected ErrInvalidToken", err)
+	}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func testJWKS(t *testing.T, rsaKey *rsa.PrivateKey, edKey ed25519.PrivateKey) []byte {
+	t.Helper()
+	doc := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa-1", "use": "sig", "alg": "RS256",
+				"n": b64(rsaKey.N.Bytes()),
+				"e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "OKP", "kid": "ed-1", "crv": "Ed25519",
+				"x": b64(edKey.Public().(ed25519.PublicKey)),
+			},
+			{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		},
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return data
+}
+
+func TestVerify_JWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, testJWKS(t, rsaKey, edKey), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	keys, err := NewJWKSFileKeySet(path)
+	if err != nil {
+		t.Fatalf("NewJWKSFileKeySet() error = %v", err)
+	}
+	v := newTestVerifier(t, keys)
+
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(nil))); err != nil {
+		t.Errorf("Verify() RS256 error = %v", err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodEdDSA, edKey, "ed-1", claims(nil))); err != nil {
+		t.Errorf("Verify() EdDSA error = %v", err)
+	}
+	// Only one key is usable with EdDSA, so the kid may be omitted
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodEdDSA, edKey, "", claims(nil))); err != nil {
+		t.Errorf("Verify() EdDSA without kid error = %v", err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, rsaKey, "unknown", claims(nil))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with unknown kid error = %v, expected ErrInvalidToken", err)
+	}
+}
+
+// wait blocks until the running refresh, if any, has finished
+func (s *RemoteKeySet) wait() {
+	s.mu.Lock()
+	r := s.inflight
+	s.mu.Unlock()
+	if r != nil {
+		<-r.done
+	}
+}
+
+func TestRemoteKeySet_Caching(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	jwks := testJWKS(t, rsaKey, edKey)
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	now := testNow
+	keys := NewRemoteKeySet(server.URL, 5*time.Minute, server.Client())
+	keys.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := keys.Key(context.Background(), "ed-1", "EdDSA"); err != nil {
+			t.Fatalf("Key() error = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 fetch while cached, got %d", got)
+	}
+
+	// Unknown kids refetch at most once per minRefreshInterval
+	if _, err := keys.Key(context.Background(), "rotated", "EdDSA"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key() error = %v, expected ErrUnknownKey", err)
+	}
+	now = now.Add(minRefreshInterval)
+	if _, err := keys.Key(context.Background(), "rotated", "EdDSA"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key() error = %v, expected ErrUnknownKey", err)
+	}
+	if _, err := keys.Key(context.Background(), "forged", "EdDSA"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key() error = %v, expected ErrUnknownKey", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected 2 fetches after unknown kids, got %d", got)
+	}
+
+	// After the TTL cached keys are still served while the refetch runs
+	now = now.Add(5 * time.Minute)
+	if _, err := keys.Key(context.Background(), "rsa-1", "RS256"); err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	keys.wait()
+	if got := fetches.Load(); got != 3 {
+		t.Errorf("Expected a refetch after the TTL, got %d fetches", got)
+	}
+}
+
+func TestRemoteKeySet_SlowRefresh(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	jwks := testJWKS(t, rsaKey, edKey)
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every fetch after the first hangs until released
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	now := testNow
+	keys := NewRemoteKeySet(server.URL, 5*time.Minute, server.Client())
+	keys.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	if _, err := keys.Key(context.Background(), "ed-1", "EdDSA"); err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+
+	mu.Lock()
+	now = now.Add(5 * time.Minute)
+	mu.Unlock()
+
+	// Callers with an unknown kid wait for the one running fetch, or give
+	// up with their context
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if _, err := keys.Key(ctx, "rotated", "EdDSA"); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Key() error = %v, expected context.DeadlineExceeded", err)
+			}
+		}()
+	}
+
+	// Cached keys are served without waiting for the stalled fetch
+	done := make(chan error)
+	go func() {
+		_, err := keys.Key(context.Background(), "rsa-1", "RS256")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Key() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Key() for a cached kid waited for the refresh")
+	}
+
+	wg.Wait()
+	close(release)
+	keys.wait()
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected a single refetch, got %d fetches", got)
+	}
+}
+
+func TestNewVerifier_Validation(t *testing.T) {
+	if _, err := NewVerifier(Config{}); err == nil {
+		t.Error("NewVerifier() without keys expected an error")
+	}
+	if _, err := NewVerifier(Config{Keys: []KeySet{NewSecretKeySet([]byte("s"))}, Algorithms: []string{"none"}}); err == nil {
+		t.Error("NewVerifier() with alg none expected an error")
+	}
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownKey  = errors.New("no key found for token")
+	ErrInvalidJWKS = errors.New("invalid JWKS")
+)
+
+// KeySet resolves the verification key for a token from its key id and
+// algorithm. An empty kid asks for the only key usable with alg.
+type KeySet interface {
+	Key(ctx context.Context, kid, alg string) (interface{}, error)
+}
+
+// jwk is a single JSON Web Key (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	// Symmetric
+	K string `json:"k"`
+}
+
+// key is a parsed JWK
+type key struct {
+	kid string
+	alg string
+	// value is a *rsa.PublicKey, ed25519.PublicKey or []byte
+	value interface{}
+}
+
+// usableWith reports whether the key can verify tokens signed with alg
+func (k key) usableWith(alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+	switch k.value.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	case []byte:
+		return alg == "HS256"
+	}
+	return false
+}
+
+// parseJWKS parses a JWK Set document. Keys of unsupported types or meant
+// for encryption are skipped.
+func parseJWKS(data []byte) ([]key, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWKS, err)
+	}
+
+	var keys []key
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var value interface{}
+		var err error
+		switch k.Kty {
+		case "RSA":
+			value, err = rsaKey(k)
+		case "OKP":
+			if k.Crv != "Ed25519" {
+				continue
+			}
+			value, err = ed25519Key(k)
+		case "oct":
+			value, err = base64.RawURLEncoding.DecodeString(k.K)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %v", ErrInvalidJWKS, k.Kid, err)
+		}
+
+		keys = append(keys, key{kid: k.Kid, alg: k.Alg, value: value})
+	}
+
+	return keys, nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid RSA modulus or exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func ed25519Key(k jwk) (ed25519.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key size")
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// findKey selects the key matching kid, or the only key usable with alg
+// when kid is empty
+func findKey(keys []key, kid, alg string) (interface{}, bool) {
+	var match *key
+	for i := range keys {
+		if !keys[i].usableWith(alg) {
+			continue
+		}
+		if kid != "" {
+			if keys[i].kid == kid {
+				return keys[i].value, true
+			}
+			continue
+		}
+		if match != nil {
+			// Ambiguous without a kid
+			return nil, false
+		}
+		match = &keys[i]
+	}
+	if match == nil {
+		return nil, false
+	}
+	return match.value, true
+}
+
+// staticKeys is a KeySet that never changes
+type staticKeys []key
+
+// NewSecretKeySet returns a KeySet holding a single HS256 shared secret,
+// used regardless of the token's kid
+func NewSecretKeySet(secret []byte) KeySet {
+	return staticKeys{{value: secret}}
+}
+
+// NewJWKSFileKeySet loads a KeySet from a JWKS file once
+func NewJWKSFileKeySet(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return staticKeys(keys), nil
+}
+
+// Key implements KeySet
+func (s staticKeys) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	if len(s) == 1 && s[0].kid == "" {
+		if s[0].usableWith(alg) {
+			return s[0].value, nil
+		}
+		return nil, ErrUnknownKey
+	}
+	if value, ok := findKey(s, kid, alg); ok {
+		return value, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// minRefreshInterval bounds how often an unknown kid can trigger a refetch
+const minRefreshInterval = 30 * time.Second
+
+// RemoteKeySet fetches a JWKS from a URL and caches it for a TTL. A token
+// with an unknown kid triggers an early refresh, so rotated keys are picked
+// up, but at most once per minRefreshInterval so forged kids cannot force a
+// fetch per request. Only one fetch runs at a time and never under the
+// lock: callers whose key is cached are answered from the cache while it
+// runs, and only callers without a usable key wait for it. When a refresh
+// fails the previously fetched keys stay in use.
+type RemoteKeySet struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        []key
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// inflight is the running refresh, if any
+	inflight *refresh
+	now      func() time.Time
+}
+
+// refresh is a single JWKS fetch; err is set before done is closed
+type refresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRemoteKeySet creates a KeySet backed by the JWKS at url
+func NewRemoteKeySet(url string, ttl time.Duration, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteKeySet{
+		url:    url,
+		ttl:    ttl,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Key implements KeySet
+func (s *RemoteKeySet) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	s.mu.Lock()
+	now := s.now()
+	if now.Sub(s.fetchedAt) >= s.ttl {
+		s.startRefresh(now)
+	}
+	if value, ok := findKey(s.keys, kid, alg); ok {
+		s.mu.Unlock()
+		return value, nil
+	}
+	// An unknown kid may be a rotated key
+	s.startRefresh(now)
+	r := s.inflight
+	s.mu.Unlock()
+	if r == nil {
+		return nil, ErrUnknownKey
+	}
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	value, ok := findKey(s.keys, kid, alg)
+	s.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return nil, ErrUnknownKey
+}
+
+// startRefresh starts a fetch in the background unless one is running or
+// the last one began less than minRefreshInterval ago. s.mu must be held.
+func (s *RemoteKeySet) startRefresh(now time.Time) {
+	if s.inflight != nil || now.Sub(s.attemptedAt) < minRefreshInterval {
+		return
+	}
+	s.attemptedAt = now
+	r := &refresh{done: make(chan struct{})}
+	s.inflight = r
+
+	go func() {
+		// The fetch is shared, so it is bounded by the client timeout
+		// rather than by the context of the request that started it
+		keys, err := s.fetch(context.Background())
+
+		s.mu.Lock()
+		if err == nil {
+			s.keys = keys
+			s.fetchedAt = now
+		}
+		r.err = err
+		s.inflight = nil
+		s.mu.Unlock()
+		close(r.done)
+	}()
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) ([]key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	return parseJWKS(data)
+}
@@ -0,0 +1,26 @@
+// Package clock abstracts the current time so that code depending on it,
+// such as age calculation, can be tested on fixed dates.
+package clock
+
+import "time"
+
+// Clock reports the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a function to the Clock interface
+type Func func() time.Time
+
+// Now calls f
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System reads the system clock
+var System Clock = Func(time.Now)
+
+// Fixed returns a Clock that always reports t
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
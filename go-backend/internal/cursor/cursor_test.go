@@ -0,0 +1,45 @@
+package cursor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec([]byte("secret"))
+	cur := Cursor{Scope: "name", Values: []string{"Alice"}, ID: 42, Backward: true}
+
+	token, err := codec.Encode(cur)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	decoded, err := codec.Decode(token)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded.Scope != cur.Scope || decoded.ID != cur.ID || !decoded.Backward || decoded.Values[0] != "Alice" {
+		t.Errorf("Decode() = %+v, expected %+v", decoded, cur)
+	}
+}
+
+func TestCodecRejectsTampering(t *testing.T) {
+	token, err := NewCodec([]byte("secret")).Encode(Cursor{ID: 1})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"wrong secret": token,
+		"no signature": "eyJpIjoxfQ",
+		"garbage":      "not-a-cursor.!!!",
+	}
+	other := NewCodec([]byte("other"))
+	for name, tok := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := other.Decode(tok); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode() error = %v, expected ErrInvalidCursor", err)
+			}
+		})
+	}
+}
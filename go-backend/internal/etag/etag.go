@@ -0,0 +1,74 @@
+package etag
+
+import (
+	"strings"
+)
+
+// MatchStrong reports whether an If-Match header value matches etag using
+// the strong comparison function of RFC 9110. Weak validators never match.
+func MatchStrong(header, etag string) bool {
+	for _, candidate := range split(header) {
+		if candidate == "*" {
+			return true
+		}
+		if !isWeak(candidate) && !isWeak(etag) && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// WithVariant returns a strong tag for one representation of the version
+// tagged version, such as a body rendered for a given date. Variants must
+// not contain quotes.
+func WithVariant(version, variant string) string {
+	return strings.TrimSuffix(version, `"`) + "-" + variant + `"`
+}
+
+// MatchVersion reports whether an If-Match header value names version or
+// any variant of it, using the strong comparison function of RFC 9110.
+// Writes apply to the stored version whichever representation the client
+// read it from.
+func MatchVersion(header, version string) bool {
+	prefix := strings.TrimSuffix(version, `"`) + "-"
+	for _, candidate := range split(header) {
+		if candidate == "*" {
+			return true
+		}
+		if isWeak(candidate) || isWeak(version) {
+			continue
+		}
+		if candidate == version || strings.HasPrefix(candidate, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchWeak reports whether an If-None-Match header value matches etag
+// using the weak comparison function of RFC 9110
+func MatchWeak(header, etag string) bool {
+	for _, candidate := range split(header) {
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isWeak(tag string) bool {
+	return strings.HasPrefix(tag, "W/")
+}
+
+func split(header string) []string {
+	var tags []string
+	for _, part := range strings.Split(header, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			tags = append(tags, part)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,71 @@
+package etag
+
+import "testing"
+
+func TestMatchStrong(t *testing.T) {
+	tests := []struct {
+		header   string
+		etag     string
+		expected bool
+	}{
+		{`"abc"`, `"abc"`, true},
+		{`"xyz", "abc"`, `"abc"`, true},
+		{`*`, `"abc"`, true},
+		{`W/"abc"`, `"abc"`, false},
+		{`"xyz"`, `"abc"`, false},
+		{``, `"abc"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchStrong(tt.header, tt.etag); got != tt.expected {
+			t.Errorf("MatchStrong(%q, %q) = %v, expected %v", tt.header, tt.etag, got, tt.expected)
+		}
+	}
+}
+
+func TestMatchWeak(t *testing.T) {
+	tests := []struct {
+		header   string
+		etag     string
+		expected bool
+	}{
+		{`"abc"`, `"abc"`, true},
+		{`W/"abc"`, `"abc"`, true},
+		{`"xyz", W/"abc"`, `"abc"`, true},
+		{`*`, `"abc"`, true},
+		{`"xyz"`, `"abc"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchWeak(tt.header, tt.etag); got != tt.expected {
+			t.Errorf("MatchWeak(%q, %q) = %v, expected %v", tt.header, tt.etag, got, tt.expected)
+		}
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	version := `"abc"`
+	variant := WithVariant(version, "20240615")
+	if variant != `"abc-20240615"` {
+		t.Fatalf("WithVariant() = %s", variant)
+	}
+
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{`"abc"`, true},
+		{variant, true},
+		{`"xyz", "abc-20240101"`, true},
+		{`*`, true},
+		{`W/"abc-20240615"`, false},
+		{`"abcd"`, false},
+		{`"xyz-20240615"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchVersion(tt.header, version); got != tt.expected {
+			t.Errorf("MatchVersion(%q, %q) = %v, expected %v", tt.header, version, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"user-api/internal/models"
+)
+
+// Supported export formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+// ContentTypes maps each export format to its content type
+var ContentTypes = map[string]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson",
+	FormatJSON:   "application/json",
+}
+
+// Encoder writes users one at a time in an export format
+type Encoder interface {
+	Encode(user models.UserResponse) error
+	// Close writes any trailing output; it must be called once all users
+	// have been encoded
+	Close() error
+}
+
+// NewEncoder creates an Encoder for the given format, or nil if the format
+// is unknown
+func NewEncoder(format string, w io.Writer, includeAge bool) Encoder {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w, includeAge)
+	case FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}
+	case FormatJSON:
+		return &jsonArrayEncoder{w: w}
+	default:
+		return nil
+	}
+}
+
+type csvEncoder struct {
+	w          *csv.Writer
+	includeAge bool
+	header     bool
+}
+
+func newCSVEncoder(w io.Writer, includeAge bool) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w), includeAge: includeAge}
+}
+
+func (e *csvEncoder) writeHeader() error {
+	e.header = true
+	header := []string{"id", "name", "dob"}
+	if e.includeAge {
+		header = append(header, "age", "age_as_of")
+	}
+	return e.w.Write(header)
+}
+
+func (e *csvEncoder) Encode(user models.UserResponse) error {
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	record := []string{strconv.FormatInt(user.ID, 10), user.Name, user.DOB}
+	if e.includeAge {
+		record = append(record, strconv.Itoa(user.Age), user.AgeAsOf)
+	}
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	// An empty export still gets its header row
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(user models.UserResponse) error {
+	return e.enc.Encode(user)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+type jsonArrayEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonArrayEncoder) Encode(user models.UserResponse) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	prefix := ","
+	if e.count == 0 {
+		prefix = "["
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonArrayEncoder) Close() error {
+	closing := "]"
+	if e.count == 0 {
+		closing = "[]"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"user-api/internal/models"
+)
+
+var testUsers = []models.UserResponse{
+	{ID: 1, Name: "Alice", DOB: "1990-05-10", Age: 34, AgeAsOf: "2024-06-15"},
+	{ID: 2, Name: "Smith, Bob", DOB: "1985-01-02", Age: 39, AgeAsOf: "2024-06-15"},
+}
+
+func encodeAll(t *testing.T, format string, includeAge bool, users []models.UserResponse) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := NewEncoder(format, &buf, includeAge)
+	for _, u := range users {
+		if err := enc.Encode(u); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		includeAge bool
+		users      []models.UserResponse
+		expected   string
+	}{
+		{"csv", FormatCSV, false, testUsers, "id,name,dob\n1,Alice,1990-05-10\n2,\"Smith, Bob\",1985-01-02\n"},
+		{"csv with age", FormatCSV, true, testUsers[:1], "id,name,dob,age,age_as_of\n1,Alice,1990-05-10,34,2024-06-15\n"},
+		{"empty csv", FormatCSV, false, nil, "id,name,dob\n"},
+		{"ndjson", FormatNDJSON, true, testUsers[:1], "{\"id\":1,\"name\":\"Alice\",\"dob\":\"1990-05-10\",\"age\":34,\"age_as_of\":\"2024-06-15\"}\n"},
+		{"json", FormatJSON, false, []models.UserResponse{{ID: 1, Name: "A", DOB: "1990-05-10"}, {ID: 2, Name: "B", DOB: "1991-05-10"}},
+			`[{"id":1,"name":"A","dob":"1990-05-10"},{"id":2,"name":"B","dob":"1991-05-10"}]`},
+		{"empty json", FormatJSON, false, nil, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeAll(t, tt.format, tt.includeAge, tt.users); got != tt.expected {
+				t.Errorf("output = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewEncoder_UnknownFormat(t *testing.T) {
+	if NewEncoder("xml", &bytes.Buffer{}, false) != nil {
+		t.Errorf("Expected nil encoder for unknown format")
+	}
+}
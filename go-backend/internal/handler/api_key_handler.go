@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"user-api/internal/apperror"
+	"user-api/internal/logger"
+	"user-api/internal/models"
+	"user-api/internal/service"
+	"user-api/internal/validation"
+)
+
+// Grants reports whether the caller of a request holds a permission
+type Grants func(c *fiber.Ctx, permission string) bool
+
+// APIKeyHandler handles HTTP requests for API key management
+type APIKeyHandler struct {
+	service   service.APIKeyService
+	validator *validation.Validator
+	grants    Grants
+	logger    *logger.Logger
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance. Callers may only
+// mint and rotate keys whose scopes grants reports they hold themselves.
+func NewAPIKeyHandler(service service.APIKeyService, validator *validation.Validator, grants Grants, logger *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		service:   service,
+		validator: validator,
+		grants:    grants,
+		logger:    logger,
+	}
+}
+
+// held checks scopes against the permissions of the caller of c
+func (h *APIKeyHandler) held(c *fiber.Ctx) service.ScopeCheck {
+	return func(scope string) bool {
+		return h.grants(c, scope)
+	}
+}
+
+// CreateAPIKey handles POST /api-keys. The plaintext key is only returned
+// in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.FromContext(c.Context()).Error("Failed to parse request body", zap.Error(err))
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidRequest, "Request body is not valid JSON", err))
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return respondInvalid(c, h.validator, err)
+	}
+
+	key, err := h.service.CreateAPIKey(c.Context(), &req, h.held(c))
+	if err != nil {
+		return respondError(c, err, "Failed to create API key")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusCreated).JSON(key)
+}
+
+// ListAPIKeys handles GET /api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.service.ListAPIKeys(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to fetch API keys")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": keys,
+	})
+}
+
+// RotateAPIKey handles POST /api-keys/:id/rotate. The old key stops working
+// immediately and the replacement is returned with its plaintext.
+func (h *APIKeyHandler) RotateAPIKey(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Invalid API key ID"))
+	}
+
+	key, err := h.service.RotateAPIKey(c.Context(), int64(id), h.held(c))
+	if err != nil {
+		return respondError(c, err, "Failed to rotate API key")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusCreated).JSON(key)
+}
+
+// RevokeAPIKey handles DELETE /api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Invalid API key ID"))
+	}
+
+	if err := h.service.RevokeAPIKey(c.Context(), int64(id)); err != nil {
+		return respondError(c, err, "Failed to revoke API key")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user-api/internal/apperror"
+	"user-api/internal/cursor"
+	"user-api/internal/models"
+	"user-api/internal/patch"
+	"user-api/internal/repository"
+	"user-api/internal/service"
+	"user-api/internal/validation"
+)
+
+// preconditionFailedDetail tells clients how to recover from a 412
+const preconditionFailedDetail = "User has been modified. Fetch the latest version and retry with its ETag"
+
+// serviceErrors maps service and repository errors to catalogue codes. An
+// empty detail uses the error message.
+var serviceErrors = []struct {
+	err    error
+	code   apperror.Code
+	detail string
+}{
+	{repository.ErrUserNotFound, apperror.CodeUserNotFound, "No user exists with this ID"},
+	{repository.ErrUserNotDeleted, apperror.CodeUserNotDeleted, "Only deleted users can be restored"},
+	{repository.ErrAPIKeyNotFound, apperror.CodeAPIKeyNotFound, "No API key exists with this ID"},
+	{repository.ErrAPIKeyRevoked, apperror.CodeAPIKeyRevoked, "API key is already revoked"},
+	{service.ErrScopeNotHeld, apperror.CodeForbidden, ""},
+	{service.ErrPreconditionFailed, apperror.CodePreconditionFailed, preconditionFailedDetail},
+	{service.ErrInvalidFilter, apperror.CodeInvalidQuery, ""},
+	{service.ErrInvalidTimezone, apperror.CodeInvalidQuery, ""},
+	{service.ErrInvalidAsOf, apperror.CodeInvalidQuery, ""},
+	{models.ErrInvalidSort, apperror.CodeInvalidQuery, ""},
+	{cursor.ErrInvalidCursor, apperror.CodeInvalidQuery, ""},
+	{patch.ErrTestFailed, apperror.CodePatchTestFailed, ""},
+	{service.ErrPatchRejected, apperror.CodeInvalidRequest, ""},
+}
+
+// respondError writes err as a problem document. Known service and
+// repository errors get their catalogue code; anything else is an internal
+// error described by fallback.
+func respondError(c *fiber.Ctx, err error, fallback string) error {
+	if errors.Is(err, service.ErrInvalidDOB) {
+		return apperror.Respond(c, validationFailed(map[string]string{
+			"dob": "dob must be a valid date in format 2006-01-02",
+		}))
+	}
+	for _, mapping := range serviceErrors {
+		if errors.Is(err, mapping.err) {
+			detail := mapping.detail
+			if detail == "" {
+				detail = err.Error()
+			}
+			return apperror.Respond(c, apperror.Wrap(mapping.code, detail, err))
+		}
+	}
+	return apperror.Respond(c, apperror.Wrap(apperror.CodeInternal, fallback, err))
+}
+
+// importRejected reports a rejected atomic import with the report's
+// members, so clients find the per-row results where a successful import
+// puts them
+func importRejected(report *models.ImportReport) *apperror.Error {
+	return apperror.New(apperror.CodeImportRejected, "No users were imported because some rows are invalid").
+		With("mode", report.Mode).
+		With("total", report.Total).
+		With("accepted", report.Accepted).
+		With("rejected", report.Rejected).
+		With("results", report.Results)
+}
+
+// negotiateLocale picks the language of validation messages from the
+// Accept-Language header and announces it in Content-Language
+func negotiateLocale(c *fiber.Ctx, v *validation.Validator) string {
+	locale := v.Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+	c.Vary(fiber.HeaderAcceptLanguage)
+	c.Set(fiber.HeaderContentLanguage, locale)
+	return locale
+}
+
+// respondInvalid reports the errors of a failed Struct validation
+func respondInvalid(c *fiber.Ctx, v *validation.Validator, err error) error {
+	return apperror.Respond(c, validationFailed(v.Errors(err, negotiateLocale(c, v))))
+}
+
+// validationFailed reports field errors as invalid parameters
+func validationFailed(fields map[string]string) *apperror.Error {
+	return apperror.New(apperror.CodeValidationFailed, "One or more fields are invalid").WithParams(fields)
+}
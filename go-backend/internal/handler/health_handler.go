@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"user-api/internal/health"
+)
+
+// HealthHandler serves the liveness, readiness and startup probes. Each
+// answers 200 or 503 with the probe status; adding ?verbose lists every
+// check with its status, latency and details.
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler creates a new HealthHandler instance
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Livez handles GET /livez. It only shows that the process serves requests
+// and never checks dependencies, so an outage does not restart the server.
+func (h *HealthHandler) Livez(c *fiber.Ctx) error {
+	return respondHealth(c, health.Report{Status: health.StatusOK})
+}
+
+// Readyz handles GET /readyz. It fails while a dependency check fails and
+// once the server has started draining.
+func (h *HealthHandler) Readyz(c *fiber.Ctx) error {
+	return respondHealth(c, h.checker.Ready(c.Context()))
+}
+
+// Startupz handles GET /startupz. It fails until every dependency check has
+// passed once.
+func (h *HealthHandler) Startupz(c *fiber.Ctx) error {
+	return respondHealth(c, h.checker.Started(c.Context()))
+}
+
+// respondHealth writes a probe report, with the checks only in verbose mode
+func respondHealth(c *fiber.Ctx, report health.Report) error {
+	status := fiber.StatusOK
+	if !report.OK() {
+		status = fiber.StatusServiceUnavailable
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	if !c.Context().QueryArgs().Has("verbose") {
+		report.Checks = nil
+	}
+	return c.Status(status).JSON(report)
+}
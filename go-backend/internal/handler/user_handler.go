@@ -1,31 +1,50 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"errors"
+	"strconv"
+	"strings"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"user-api/internal/apperror"
+	"user-api/internal/etag"
+	"user-api/internal/export"
+	"user-api/internal/importer"
 	"user-api/internal/logger"
 	"user-api/internal/models"
-	"user-api/internal/repository"
+	"user-api/internal/patch"
 	"user-api/internal/service"
+	"user-api/internal/validation"
 )
 
-var validate = validator.New()
+// maxImportRecords caps the number of rows accepted by a single import
+const maxImportRecords = 10000
+
+// exportFlushInterval is the number of exported rows buffered between flushes
+const exportFlushInterval = 100
+
+// readOnlyFields are members of the user representation a patch may not
+// change
+var readOnlyFields = map[string]bool{"id": true, "age": true, "age_as_of": true}
 
 // UserHandler handles HTTP requests for user operations
 type UserHandler struct {
-	service service.UserService
-	logger  *logger.Logger
+	service   service.UserService
+	validator *validation.Validator
+	logger    *logger.Logger
 }
 
 // NewUserHandler creates a new UserHandler instance
-func NewUserHandler(service service.UserService, logger *logger.Logger) *UserHandler {
+func NewUserHandler(service service.UserService, validator *validation.Validator, logger *logger.Logger) *UserHandler {
 	return &UserHandler{
-		service: service,
-		logger:  logger,
+		service:   service,
+		validator: validator,
+		logger:    logger,
 	}
 }
 
@@ -34,55 +53,208 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 	var req models.CreateUserRequest
 
 	if err := c.BodyParser(&req); err != nil {
-		h.logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		h.logger.FromContext(c.Context()).Error("Failed to parse request body", zap.Error(err))
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidRequest, "Request body is not valid JSON", err))
 	}
 
 	// Validate request
-	if err := validate.Struct(&req); err != nil {
-		validationErrors := formatValidationErrors(err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validationErrors,
-		})
+	if err := h.validator.Struct(&req); err != nil {
+		return respondInvalid(c, h.validator, err)
 	}
 
 	user, err := h.service.CreateUser(c.Context(), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidDOB) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid date of birth format. Use YYYY-MM-DD",
-			})
+		return respondError(c, err, "Failed to create user")
+	}
+
+	c.Set(fiber.HeaderETag, user.ETag)
+	return c.Status(fiber.StatusCreated).JSON(user)
+}
+
+// ImportUsers handles POST /users:import. The body is CSV (text/csv) with a
+// name,dob header or NDJSON (application/x-ndjson). The mode query parameter
+// selects atomic (default) or best_effort handling of invalid rows.
+func (h *UserHandler) ImportUsers(c *fiber.Ctx) error {
+	mode := models.ImportMode(c.Query("mode", string(models.ImportModeAtomic)))
+	if mode != models.ImportModeAtomic && mode != models.ImportModeBestEffort {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidQuery, "Invalid mode. Use atomic or best_effort"))
+	}
+
+	var records []importer.Record
+	var err error
+	switch mediaType(c.Get(fiber.HeaderContentType)) {
+	case importer.CSVContentType:
+		records, err = importer.ParseCSV(bytes.NewReader(c.Body()), maxImportRecords)
+	case importer.NDJSONContentType:
+		records, err = importer.ParseNDJSON(bytes.NewReader(c.Body()), maxImportRecords)
+	default:
+		return apperror.Respond(c, apperror.New(apperror.CodeUnsupportedMediaType, "Unsupported import format. Use "+importer.CSVContentType+" or "+importer.NDJSONContentType))
+	}
+	if err != nil {
+		if errors.Is(err, importer.ErrTooManyRecords) {
+			return apperror.Respond(c, apperror.New(apperror.CodePayloadTooLarge, "Too many records, the limit is "+strconv.Itoa(maxImportRecords)))
+		}
+		h.logger.FromContext(c.Context()).Error("Failed to parse import", zap.Error(err))
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidRequest, err.Error(), err))
+	}
+	if len(records) == 0 {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Import contains no records"))
+	}
+
+	// Validate every row with the same rules as CreateUser
+	locale := negotiateLocale(c, h.validator)
+	rows := make([]models.ImportRow, len(records))
+	for i, record := range records {
+		rows[i] = models.ImportRow{
+			Row:     record.Row,
+			Request: models.CreateUserRequest{Name: record.Name, DOB: record.DOB},
+		}
+		if record.Err != nil {
+			rows[i].Errors = map[string]string{"row": record.Err.Error()}
+		} else if err := h.validator.Struct(&rows[i].Request); err != nil {
+			rows[i].Errors = h.validator.Errors(err, locale)
+		}
+	}
+
+	report, err := h.service.ImportUsers(c.Context(), rows, mode)
+	if err != nil {
+		if errors.Is(err, service.ErrImportRejected) {
+			return apperror.Respond(c, importRejected(report))
+		}
+		return respondError(c, err, "Failed to import users")
+	}
+
+	return c.JSON(report)
+}
+
+// ExportUsers handles GET /users:export. Users are streamed from the
+// database straight into the response as CSV, NDJSON or a JSON array,
+// chosen by the format query parameter or the Accept header. The list
+// filters and sort apply; include_age=true adds the computed age.
+func (h *UserHandler) ExportUsers(c *fiber.Ctx) error {
+	format := c.Query("format")
+	if format == "" {
+		switch c.Accepts(fiber.MIMEApplicationJSON, importer.NDJSONContentType, importer.CSVContentType) {
+		case fiber.MIMEApplicationJSON:
+			format = export.FormatJSON
+		case importer.NDJSONContentType:
+			format = export.FormatNDJSON
+		case importer.CSVContentType:
+			format = export.FormatCSV
+		default:
+			return apperror.Respond(c, apperror.New(apperror.CodeNotAcceptable, "Export is available as text/csv, application/x-ndjson or application/json"))
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create user",
+	}
+	contentType, ok := export.ContentTypes[format]
+	if !ok {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidQuery, "Invalid format. Use csv, ndjson or json"))
+	}
+
+	var query models.ListUsersQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidQuery, "Invalid query parameters", err))
+	}
+	if err := h.validateListQuery(c, &query); err != nil {
+		return apperror.Respond(c, err)
+	}
+
+	includeAge := c.QueryBool("include_age")
+	stream, err := h.service.ExportUsers(c.Context(), &query, includeAge)
+	if err != nil {
+		return respondError(c, err, "Failed to export users")
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="users.`+format+`"`)
+
+	// The stream writer runs after the handler returns, so it cannot use the
+	// request context; only the request logger is carried over
+	log := h.logger.FromContext(c.Context())
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(logger.NewContext(context.Background(), log))
+		defer cancel()
+
+		enc := export.NewEncoder(format, w, includeAge)
+		written := 0
+		err := stream(ctx, func(user models.UserResponse) error {
+			if err := enc.Encode(user); err != nil {
+				return err
+			}
+			// Flush periodically so rows reach the client as they are read
+			if written++; written%exportFlushInterval == 0 {
+				return w.Flush()
+			}
+			return nil
 		})
+		if err != nil {
+			// Headers are already sent; a truncated body signals the failure
+			log.Error("Export aborted", zap.Error(err))
+			return
+		}
+		if err := enc.Close(); err != nil {
+			log.Error("Failed to finish export", zap.Error(err))
+			return
+		}
+		_ = w.Flush()
+	})
+
+	return nil
+}
+
+// ListBirthdays handles GET /users/birthdays. Users whose next birthday is
+// within_days from today, or between from and to, are listed soonest first.
+func (h *UserHandler) ListBirthdays(c *fiber.Ctx) error {
+	var query models.BirthdaysQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidQuery, "Invalid query parameters", err))
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(user)
+	// Apply pagination defaults
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 10
+	}
+	if query.PageSize > 100 {
+		query.PageSize = 100
+	}
+
+	if err := h.validator.Struct(&query); err != nil {
+		return respondInvalid(c, h.validator, err)
+	}
+
+	result, err := h.service.ListBirthdays(c.Context(), &query)
+	if err != nil {
+		return respondError(c, err, "Failed to list birthdays")
+	}
+
+	return c.JSON(result)
 }
 
 // GetUser handles GET /users/:id
 func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Invalid user ID"))
+	}
+
+	var query models.GetUserQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidQuery, "Invalid query parameters", err))
+	}
+	if err := h.validator.Struct(&query); err != nil {
+		return respondInvalid(c, h.validator, err)
 	}
 
-	user, err := h.service.GetUser(c.Context(), int64(id))
+	user, err := h.service.GetUser(c.Context(), int64(id), &query)
 	if err != nil {
-		if errors.Is(err, repository.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch user",
-		})
+		return respondError(c, err, "Failed to fetch user")
+	}
+
+	c.Set(fiber.HeaderETag, user.ETag)
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" && etag.MatchWeak(ifNoneMatch, user.ETag) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
 	return c.JSON(user)
@@ -92,45 +264,89 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Invalid user ID"))
 	}
 
 	var req models.UpdateUserRequest
 	if err := c.BodyParser(&req); err != nil {
-		h.logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		h.logger.FromContext(c.Context()).Error("Failed to parse request body", zap.Error(err))
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidRequest, "Request body is not valid JSON", err))
 	}
 
 	// Validate request
-	if err := validate.Struct(&req); err != nil {
-		validationErrors := formatValidationErrors(err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validationErrors,
-		})
+	if err := h.validator.Struct(&req); err != nil {
+		return respondInvalid(c, h.validator, err)
 	}
 
-	user, err := h.service.UpdateUser(c.Context(), int64(id), &req)
+	user, err := h.service.UpdateUser(c.Context(), int64(id), &req, c.Get(fiber.HeaderIfMatch))
 	if err != nil {
-		if errors.Is(err, repository.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
+		return respondError(c, err, "Failed to update user")
+	}
+
+	c.Set(fiber.HeaderETag, user.ETag)
+	return c.JSON(user)
+}
+
+// PatchUser handles PATCH /users/:id. The body is a JSON Merge Patch
+// (application/merge-patch+json or application/json) or a JSON Patch
+// (application/json-patch+json) applied to the user's JSON representation.
+func (h *UserHandler) PatchUser(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Invalid user ID"))
+	}
+
+	var p patch.Patch
+	switch mediaType(c.Get(fiber.HeaderContentType)) {
+	case patch.MergePatchContentType, fiber.MIMEApplicationJSON:
+		p, err = patch.ParseMergePatch(c.Body())
+	case patch.JSONPatchContentType:
+		p, err = patch.ParseJSONPatch(c.Body())
+	default:
+		c.Set("Accept-Patch", patch.MergePatchContentType+", "+patch.JSONPatchContentType)
+		return apperror.Respond(c, apperror.New(apperror.CodeUnsupportedMediaType, "Unsupported patch format. Use "+patch.MergePatchContentType+" or "+patch.JSONPatchContentType))
+	}
+	if err != nil {
+		h.logger.FromContext(c.Context()).Error("Failed to parse patch document", zap.Error(err))
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidRequest, "Invalid patch document", err))
+	}
+
+	locale := negotiateLocale(c, h.validator)
+	for _, field := range p.Members() {
+		if readOnlyFields[field] {
+			return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, h.validator.Message(locale, validation.KeyReadOnly, field)))
+		}
+	}
+
+	build := func(current models.UserResponse) (*models.PatchUserRequest, error) {
+		req, details, err := buildPatchRequest(&current, p)
+		if err != nil {
+			return nil, err
 		}
-		if errors.Is(err, service.ErrInvalidDOB) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid date of birth format. Use YYYY-MM-DD",
-			})
+		if details != nil {
+			return nil, details
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update user",
-		})
+		// Validate supplied fields
+		return req, h.validator.Struct(req)
 	}
 
+	user, err := h.service.PatchUser(c.Context(), int64(id), build, c.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		var details patchDetails
+		switch {
+		case errors.As(err, &details):
+			fields := make(map[string]string, len(details))
+			for field, key := range details {
+				fields[field] = h.validator.Message(locale, key, field)
+			}
+			return apperror.Respond(c, validationFailed(fields))
+		case h.validator.Errors(err, locale) != nil:
+			return respondInvalid(c, h.validator, err)
+		}
+		return respondError(c, err, "Failed to patch user")
+	}
+
+	c.Set(fiber.HeaderETag, user.ETag)
 	return c.JSON(user)
 }
 
@@ -138,72 +354,179 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Invalid user ID"))
 	}
 
-	err = h.service.DeleteUser(c.Context(), int64(id))
+	err = h.service.DeleteUser(c.Context(), int64(id), c.Get(fiber.HeaderIfMatch))
 	if err != nil {
-		if errors.Is(err, repository.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete user",
-		})
+		return respondError(c, err, "Failed to delete user")
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// RestoreUser handles POST /users/:id/restore
+func (h *UserHandler) RestoreUser(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Invalid user ID"))
+	}
+
+	user, err := h.service.RestoreUser(c.Context(), int64(id), c.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return respondError(c, err, "Failed to restore user")
+	}
+
+	c.Set(fiber.HeaderETag, user.ETag)
+	return c.JSON(user)
+}
+
 // ListUsers handles GET /users
 func (h *UserHandler) ListUsers(c *fiber.Ctx) error {
-	// Parse pagination query params with defaults
-	page := c.QueryInt("page", 1)
-	pageSize := c.QueryInt("page_size", 10)
+	var query models.ListUsersQuery
+	if err := c.QueryParser(&query); err != nil {
+		return apperror.Respond(c, apperror.Wrap(apperror.CodeInvalidQuery, "Invalid query parameters", err))
+	}
+
+	// Cursor mode is selected by either a cursor or an explicit limit
+	if query.Cursor != "" || c.Query("limit") != "" {
+		return h.listUsersCursor(c, &query)
+	}
 
-	// Validate pagination params
-	if page < 1 {
-		page = 1
+	// Apply pagination defaults
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 10
 	}
-	if pageSize < 1 {
-		pageSize = 10
+	if query.PageSize > 100 {
+		query.PageSize = 100
 	}
-	if pageSize > 100 {
-		pageSize = 100
+
+	if err := h.validateListQuery(c, &query); err != nil {
+		return apperror.Respond(c, err)
 	}
 
-	result, err := h.service.ListUsers(c.Context(), page, pageSize)
+	result, err := h.service.ListUsers(c.Context(), &query)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to list users",
-		})
+		return respondError(c, err, "Failed to list users")
 	}
 
 	return c.JSON(result)
 }
 
-// formatValidationErrors converts validator errors to readable format
-func formatValidationErrors(err error) map[string]string {
-	errors := make(map[string]string)
-
-	for _, err := range err.(validator.ValidationErrors) {
-		field := err.Field()
-		switch err.Tag() {
-		case "required":
-			errors[field] = field + " is required"
-		case "min":
-			errors[field] = field + " must be at least " + err.Param() + " characters"
-		case "max":
-			errors[field] = field + " must be at most " + err.Param() + " characters"
-		case "datetime":
-			errors[field] = field + " must be in format " + err.Param()
+// listUsersCursor handles GET /users in keyset pagination mode
+func (h *UserHandler) listUsersCursor(c *fiber.Ctx, query *models.ListUsersQuery) error {
+	if query.Page != 0 || query.PageSize != 0 {
+		return apperror.Respond(c, apperror.New(apperror.CodeInvalidQuery, "page and page_size cannot be combined with cursor or limit"))
+	}
+
+	// Apply limit defaults
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+	if query.Limit > 100 {
+		query.Limit = 100
+	}
+
+	if err := h.validateListQuery(c, query); err != nil {
+		return apperror.Respond(c, err)
+	}
+
+	result, err := h.service.ListUsersCursor(c.Context(), query)
+	if err != nil {
+		return respondError(c, err, "Failed to list users")
+	}
+
+	return c.JSON(result)
+}
+
+// validateListQuery validates list filters and sort
+func (h *UserHandler) validateListQuery(c *fiber.Ctx, query *models.ListUsersQuery) *apperror.Error {
+	// Validate filters
+	if err := h.validator.Struct(query); err != nil {
+		return validationFailed(h.validator.Errors(err, negotiateLocale(c, h.validator)))
+	}
+
+	// Validate sort fields against the whitelist
+	if _, err := models.ParseSort(query.Sort); err != nil {
+		return apperror.New(apperror.CodeInvalidQuery, "Invalid sort parameter").WithParams(map[string]string{"sort": err.Error()})
+	}
+
+	return nil
+}
+
+// patchDetails maps the fields a patch left invalid to validation message
+// keys
+type patchDetails map[string]string
+
+func (d patchDetails) Error() string {
+	return "patched document has invalid fields"
+}
+
+// buildPatchRequest applies a patch to the user's representation and returns
+// a request holding only the fields it changed. Field-level problems, such
+// as removing a required field, are returned as details mapping the field
+// to a validation message key. Patches to read-only fields are rejected
+// before this is called, so their members are only here for test operations.
+func buildPatchRequest(current *models.UserResponse, p patch.Patch) (*models.PatchUserRequest, patchDetails, error) {
+	original := map[string]interface{}{
+		"id":   float64(current.ID),
+		"name": current.Name,
+		"dob":  current.DOB,
+	}
+
+	result, err := p.Apply(original)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.New("patched document must be a JSON object")
+	}
+
+	req := &models.PatchUserRequest{}
+	details := make(patchDetails)
+	for field, value := range doc {
+		switch field {
+		case "name", "dob":
+			if value == original[field] {
+				continue
+			}
+			str, ok := value.(string)
+			if !ok {
+				details[field] = validation.KeyString
+				continue
+			}
+			if field == "name" {
+				req.Name = &str
+			} else {
+				req.DOB = &str
+			}
+		case "id":
+			// Unchanged, since patches naming it were rejected
 		default:
-			errors[field] = field + " is invalid"
+			details[field] = validation.KeyUnknownField
+		}
+	}
+	for _, field := range []string{"name", "dob"} {
+		if _, ok := doc[field]; !ok {
+			details[field] = "required"
 		}
 	}
 
-	return errors
+	if len(details) > 0 {
+		return nil, details, nil
+	}
+	return req, nil, nil
+}
+
+// mediaType strips parameters such as charset from a Content-Type value
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
 }
@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"user-api/internal/migrate"
+)
+
+var ErrPendingMigrations = errors.New("schema has pending migrations")
+
+// Database pings the database and reports the connection pool usage.
+// Saturation is the fraction of the maximum open connections in use; a
+// saturated pool is reported but does not fail the check by itself.
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) (map[string]any, error) {
+		err := db.PingContext(ctx)
+
+		stats := db.Stats()
+		details := map[string]any{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+			"max_open":         stats.MaxOpenConnections,
+			"wait_count":       stats.WaitCount,
+			"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+		}
+		if stats.MaxOpenConnections > 0 {
+			details["saturation"] = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		}
+		return details, err
+	}
+}
+
+// Migrations fails while the applied schema version is behind expected,
+// the latest migration this build knows about
+func Migrations(db *sql.DB, expected int64) Check {
+	return func(ctx context.Context) (map[string]any, error) {
+		version, err := migrate.CurrentVersion(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		details := map[string]any{
+			"version":  version,
+			"expected": expected,
+		}
+		if version < expected {
+			return details, fmt.Errorf("%w: at version %d, expected %d", ErrPendingMigrations, version, expected)
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,145 @@
+// Package health runs the dependency checks behind the liveness, readiness
+// and startup probes.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the outcome of a probe or of a single check
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+	// StatusDraining is reported by readiness once shutdown has begun
+	StatusDraining Status = "draining"
+)
+
+// Check reports whether a dependency is usable. The returned details are
+// included in verbose reports, also when the check fails.
+type Check func(ctx context.Context) (map[string]any, error)
+
+// Result is the outcome of a single check
+type Result struct {
+	Name      string         `json:"name"`
+	Status    Status         `json:"status"`
+	LatencyMS float64        `json:"latency_ms"`
+	Details   map[string]any `json:"details,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// Report is the outcome of a probe
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+// OK reports whether the probe passed
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the registered checks for the readiness and startup probes
+type Checker struct {
+	timeout  time.Duration
+	checks   []namedCheck
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+// NewChecker creates a Checker that gives each check up to timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a check run by Ready and Started. Register must not be
+// called once the probes are being served.
+func (c *Checker) Register(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain makes readiness fail from now on, so load balancers stop routing
+// requests to the server before it shuts down
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Ready runs every check. It reports StatusDraining without running the
+// checks once Drain has been called.
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.Draining() {
+		return Report{Status: StatusDraining}
+	}
+	return c.run(ctx)
+}
+
+// Started runs every check until they first all pass and reports StatusOK
+// from then on without running them again
+func (c *Checker) Started(ctx context.Context) Report {
+	if c.started.Load() {
+		return Report{Status: StatusOK}
+	}
+	report := c.run(ctx)
+	if report.OK() {
+		c.started.Store(true)
+	}
+	return report
+}
+
+// run executes the checks concurrently, each bounded by the timeout
+func (c *Checker) run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, nc)
+		}(i, nc)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (c *Checker) runCheck(ctx context.Context, nc namedCheck) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	details, err := nc.check(ctx)
+	result := Result{
+		Name:      nc.name,
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
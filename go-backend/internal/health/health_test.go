@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	failing := true
+	checker := NewChecker(10 * time.Millisecond)
+	checker.Register("fast", func(ctx context.Context) (map[string]any, error) {
+		return map[string]any{"version": 4}, nil
+	})
+	checker.Register("flaky", func(ctx context.Context) (map[string]any, error) {
+		if failing {
+			return nil, errors.New("unreachable")
+		}
+		return nil, nil
+	})
+
+	report := checker.Ready(context.Background())
+	if report.Status != StatusFail || len(report.Checks) != 2 {
+		t.Fatalf("Ready() = %+v, expected fail with 2 checks", report)
+	}
+	if report.Checks[0].Status != StatusOK || report.Checks[0].Details["version"] != 4 {
+		t.Errorf("fast check = %+v, expected ok with details", report.Checks[0])
+	}
+	if report.Checks[1].Status != StatusFail || report.Checks[1].Error != "unreachable" {
+		t.Errorf("flaky check = %+v, expected fail with error", report.Checks[1])
+	}
+
+	failing = false
+	if report := checker.Ready(context.Background()); !report.OK() {
+		t.Errorf("Ready() = %+v, expected ok", report)
+	}
+
+	checker.Drain()
+	if report := checker.Ready(context.Background()); report.Status != StatusDraining || report.OK() {
+		t.Errorf("Ready() while draining = %+v, expected draining", report)
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := NewChecker(10 * time.Millisecond)
+	checker.Register("slow", func(ctx context.Context) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	report := checker.Ready(context.Background())
+	if report.OK() || report.Checks[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Ready() = %+v, expected the check to time out", report)
+	}
+}
+
+func TestChecker_Started(t *testing.T) {
+	calls := 0
+	checker := NewChecker(time.Second)
+	checker.Register("db", func(ctx context.Context) (map[string]any, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("starting up")
+		}
+		return nil, nil
+	})
+
+	if report := checker.Started(context.Background()); report.OK() {
+		t.Fatalf("Started() = %+v, expected fail before the first success", report)
+	}
+	if report := checker.Started(context.Background()); !report.OK() {
+		t.Fatalf("Started() = %+v, expected ok", report)
+	}
+
+	// Once started the checks are not run again, even while draining
+	checker.Drain()
+	if report := checker.Started(context.Background()); !report.OK() || calls != 2 {
+		t.Errorf("Started() = %+v after %d calls, expected ok without running checks", report, calls)
+	}
+}
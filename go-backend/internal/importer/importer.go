@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrMissingColumns = errors.New("CSV header must contain name and dob columns")
+	ErrTooManyRecords = errors.New("too many records")
+)
+
+// Content types accepted for imports
+const (
+	CSVContentType    = "text/csv"
+	NDJSONContentType = "application/x-ndjson"
+)
+
+// Record is a single parsed input row
+type Record struct {
+	// Row is the 1-based position of the record in the input, not counting
+	// the CSV header
+	Row  int
+	Name string
+	DOB  string
+	// Err is set when the row itself could not be parsed
+	Err error
+}
+
+// ParseCSV parses CSV input with a header row naming the name and dob
+// columns, in any order. Other columns are ignored.
+func ParseCSV(r io.Reader, maxRecords int) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrMissingColumns
+		}
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	nameCol, dobCol := -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))) {
+		case "name":
+			nameCol = i
+		case "dob":
+			dobCol = i
+		}
+	}
+	if nameCol < 0 || dobCol < 0 {
+		return nil, ErrMissingColumns
+	}
+
+	var records []Record
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(records) == maxRecords {
+			return nil, ErrTooManyRecords
+		}
+
+		record := Record{Row: row}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			record.Err = parseErr.Err
+		case err != nil:
+			return nil, err
+		case len(fields) <= nameCol || len(fields) <= dobCol:
+			record.Err = fmt.Errorf("expected at least %d fields, got %d", max(nameCol, dobCol)+1, len(fields))
+		default:
+			record.Name = strings.TrimSpace(fields[nameCol])
+			record.DOB = strings.TrimSpace(fields[dobCol])
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ParseNDJSON parses newline-delimited JSON objects with name and dob
+// members. Blank lines are skipped.
+func ParseNDJSON(r io.Reader, maxRecords int) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var records []Record
+	row := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(records) == maxRecords {
+			return nil, ErrTooManyRecords
+		}
+		row++
+
+		var fields struct {
+			Name string `json:"name"`
+			DOB  string `json:"dob"`
+		}
+		record := Record{Row: row}
+		if err := json.Unmarshal(line, &fields); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %w", err)
+		} else {
+			record.Name = fields.Name
+			record.DOB = fields.DOB
+		}
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "DOB,Name,team\n1990-05-10,Alice,x\n\"1985-01-02\",\"Smith, Bob\",y\nonly-one-field\n"
+
+	records, err := ParseCSV(strings.NewReader(input), 10)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[0].Name != "Alice" || records[0].DOB != "1990-05-10" || records[0].Row != 1 {
+		t.Errorf("Unexpected first record: %+v", records[0])
+	}
+	if records[1].Name != "Smith, Bob" {
+		t.Errorf("Expected quoted name, got %q", records[1].Name)
+	}
+	if records[2].Err == nil || records[2].Row != 3 {
+		t.Errorf("Expected row 3 to have a parse error, got %+v", records[2])
+	}
+}
+
+func TestParseCSV_Errors(t *testing.T) {
+	if _, err := ParseCSV(strings.NewReader("name,birthday\nAlice,1990-05-10\n"), 10); !errors.Is(err, ErrMissingColumns) {
+		t.Errorf("ParseCSV() without dob column error = %v, expected ErrMissingColumns", err)
+	}
+	if _, err := ParseCSV(strings.NewReader(""), 10); !errors.Is(err, ErrMissingColumns) {
+		t.Errorf("ParseCSV() of empty input error = %v, expected ErrMissingColumns", err)
+	}
+	if _, err := ParseCSV(strings.NewReader("name,dob\na,1990-01-01\nb,1990-01-01\n"), 1); !errors.Is(err, ErrTooManyRecords) {
+		t.Errorf("ParseCSV() over the limit error = %v, expected ErrTooManyRecords", err)
+	}
+}
+
+func TestParseNDJSON(t *testing.T) {
+	input := `{"name":"Alice","dob":"1990-05-10"}
+
+{"name":"Bob"
+{"name":"Carol","dob":"2001-12-31"}
+`
+
+	records, err := ParseNDJSON(strings.NewReader(input), 10)
+	if err != nil {
+		t.Fatalf("ParseNDJSON() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[0].Name != "Alice" || records[0].Err != nil {
+		t.Errorf("Unexpected first record: %+v", records[0])
+	}
+	if records[1].Err == nil || records[1].Row != 2 {
+		t.Errorf("Expected row 2 to have a parse error, got %+v", records[1])
+	}
+	if records[2].Name != "Carol" || records[2].Row != 3 {
+		t.Errorf("Unexpected third record: %+v", records[2])
+	}
+}
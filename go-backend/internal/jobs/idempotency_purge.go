@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"user-api/internal/logger"
+	"user-api/internal/repository"
+)
+
+// IdempotencyPurgeJob periodically deletes expired idempotency keys
+type IdempotencyPurgeJob struct {
+	repo     repository.IdempotencyRepository
+	interval time.Duration
+	logger   *logger.Logger
+}
+
+// NewIdempotencyPurgeJob creates a new IdempotencyPurgeJob instance
+func NewIdempotencyPurgeJob(repo repository.IdempotencyRepository, interval time.Duration, logger *logger.Logger) *IdempotencyPurgeJob {
+	return &IdempotencyPurgeJob{
+		repo:     repo,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run purges once immediately and then on every interval until ctx is done
+func (j *IdempotencyPurgeJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *IdempotencyPurgeJob) purge(ctx context.Context) {
+	purged, err := j.repo.PurgeExpired(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			j.logger.Error("Failed to purge idempotency keys", zap.Error(err))
+		}
+		return
+	}
+	if purged > 0 {
+		j.logger.Info("Purged expired idempotency keys", zap.Int64("count", purged))
+	}
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"user-api/internal/logger"
+	"user-api/internal/service"
+)
+
+// PurgeJob periodically and permanently removes users that were soft
+// deleted longer ago than the retention window
+type PurgeJob struct {
+	service   service.UserService
+	interval  time.Duration
+	retention time.Duration
+	logger    *logger.Logger
+}
+
+// NewPurgeJob creates a new PurgeJob instance
+func NewPurgeJob(service service.UserService, interval, retention time.Duration, logger *logger.Logger) *PurgeJob {
+	return &PurgeJob{
+		service:   service,
+		interval:  interval,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Run purges once immediately and then on every interval until ctx is done
+func (j *PurgeJob) Run(ctx context.Context) {
+	j.logger.Info("Starting purge job",
+		zap.Duration("interval", j.interval),
+		zap.Duration("retention", j.retention),
+	)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			j.logger.Info("Purge job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *PurgeJob) purge(ctx context.Context) {
+	// Errors are logged by the service; the next tick retries
+	_, _ = j.service.PurgeDeletedUsers(ctx, time.Now().Add(-j.retention))
+}
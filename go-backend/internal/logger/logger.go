@@ -1,15 +1,26 @@
 package logger
 
 import (
+	"context"
 	"os"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"user-api/internal/tracing"
 )
 
+// LocalsKey is the key under which the request-scoped logger is stored. It
+// is a plain string so that a logger stored with Fiber's c.Locals is also
+// visible through the request context passed to the service layer.
+const LocalsKey = "logger"
+
 // Logger wraps zap.Logger with custom methods
 type Logger struct {
 	*zap.Logger
+	// untraced is the logger before FromContext added trace fields, so
+	// that they are replaced rather than repeated for nested spans
+	untraced *zap.Logger
 }
 
 // NewLogger creates a new Uber Zap logger instance
@@ -46,7 +57,7 @@ func NewLogger() *Logger {
 		zap.AddStacktrace(zapcore.ErrorLevel),
 	)
 
-	return &Logger{logger}
+	return &Logger{Logger: logger}
 }
 
 func getLogLevel() zapcore.Level {
@@ -93,10 +104,53 @@ func (l *Logger) Fatal(msg string, err error, fields ...zap.Field) {
 
 // WithRequestID returns a logger with request ID field
 func (l *Logger) WithRequestID(requestID string) *Logger {
-	return &Logger{l.Logger.With(zap.String("request_id", requestID))}
+	return &Logger{Logger: l.base().With(zap.String("request_id", requestID))}
 }
 
 // WithUserID returns a logger with user ID field
 func (l *Logger) WithUserID(userID int64) *Logger {
-	return &Logger{l.Logger.With(zap.Int64("user_id", userID))}
+	return &Logger{Logger: l.base().With(zap.Int64("user_id", userID))}
+}
+
+// WithCaller returns a logger with the authenticated caller's subject
+func (l *Logger) WithCaller(subject string) *Logger {
+	return &Logger{Logger: l.base().With(zap.String("subject", subject))}
+}
+
+// NewContext returns a copy of ctx carrying l
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, LocalsKey, l)
+}
+
+// FromContext returns the request-scoped logger carried by ctx, or l when
+// there is none. When ctx carries a span its trace_id and span_id are
+// added so log lines can be matched with traces.
+func (l *Logger) FromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return l
+	}
+
+	scoped := l
+	if s, ok := ctx.Value(LocalsKey).(*Logger); ok {
+		scoped = s
+	}
+
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		return &Logger{
+			Logger: scoped.base().With(
+				zap.String("trace_id", sc.TraceID().String()),
+				zap.String("span_id", sc.SpanID().String()),
+			),
+			untraced: scoped.base(),
+		}
+	}
+	return scoped
+}
+
+// base returns the logger without trace fields
+func (l *Logger) base() *zap.Logger {
+	if l.untraced != nil {
+		return l.untraced
+	}
+	return l.Logger
 }
@@ -0,0 +1,84 @@
+// Package metrics defines the Prometheus metrics served on /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the application's collectors in their own registry
+type Metrics struct {
+	registry          *prometheus.Registry
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	serviceOperations *prometheus.CounterVec
+	serviceErrors     *prometheus.CounterVec
+}
+
+// New creates the application metrics together with the Go runtime and
+// process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route template and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		serviceOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_service_operations_total",
+			Help: "User service operations by name.",
+		}, []string{"operation"}),
+		serviceErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_service_errors_total",
+			Help: "Failed user service operations by name and reason.",
+		}, []string{"operation", "reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.serviceOperations,
+		m.serviceErrors,
+	)
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db, labelled with
+// name: open, in-use and idle connections and the wait count and duration
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records a finished HTTP request
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// ObserveOperation records a service operation. A non-empty reason marks
+// the operation as failed.
+func (m *Metrics) ObserveOperation(operation, reason string) {
+	m.serviceOperations.WithLabelValues(operation).Inc()
+	if reason != "" {
+		m.serviceErrors.WithLabelValues(operation, reason).Inc()
+	}
+}
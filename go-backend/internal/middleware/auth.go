@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"user-api/internal/apperror"
+	"user-api/internal/auth"
+	"user-api/internal/logger"
+)
+
+// Locals keys holding the verified caller
+const (
+	LocalsSubject = "subject"
+	LocalsClaims  = "claims"
+)
+
+// JWTAuth requires a valid bearer token on every request. The verified
+// subject and claims are stored in c.Locals under LocalsSubject and
+// LocalsClaims, and the request logger is tagged with the subject.
+// Requests without a valid token get 401 with a WWW-Authenticate challenge.
+// Requests already authenticated by an API key are let through.
+func JWTAuth(verifier *auth.Verifier, log *logger.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if Subject(c) != "" {
+			return c.Next()
+		}
+
+		token, err := bearerToken(c.Get(fiber.HeaderAuthorization))
+		if err != nil {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer`)
+			return apperror.Respond(c, apperror.New(apperror.CodeUnauthorized, "A bearer token or API key is required"))
+		}
+
+		identity, err := verifier.Verify(c.Context(), token)
+		if err != nil {
+			log.FromContext(c.Context()).Warn("Rejected bearer token", zap.Error(err))
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return apperror.Respond(c, apperror.Wrap(apperror.CodeUnauthorized, "Invalid or expired token", err))
+		}
+
+		c.Locals(LocalsSubject, identity.Subject)
+		c.Locals(LocalsClaims, identity.Claims)
+		c.Locals(logger.LocalsKey, log.FromContext(c.Context()).WithCaller(identity.Subject))
+
+		return c.Next()
+	}
+}
+
+// bearerToken extracts the token from an Authorization header
+func bearerToken(header string) (string, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", auth.ErrMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// Subject returns the authenticated caller's subject, or "" when the
+// request was not authenticated
+func Subject(c *fiber.Ctx) string {
+	subject, _ := c.Locals(LocalsSubject).(string)
+	return subject
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"user-api/internal/auth"
+	"user-api/internal/logger"
+)
+
+func newAuthApp(t *testing.T, secret []byte) *fiber.App {
+	t.Helper()
+	verifier, err := auth.NewVerifier(auth.Config{Keys: []auth.KeySet{auth.NewSecretKeySet(secret)}})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(RequestID())
+	app.Use(LoggerMiddleware(logger.NewLogger()))
+	app.Use(JWTAuth(verifier, logger.NewLogger()))
+	app.Get("/whoami", func(c *fiber.Ctx) error {
+		return c.SendString(Subject(c))
+	})
+	return app
+}
+
+func TestJWTAuth(t *testing.T) {
+	secret := []byte("test-secret")
+	app := newAuthApp(t, secret)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+		wantSubject   string
+	}{
+		{"valid token", "Bearer " + token, fiber.StatusOK, "", "user-42"},
+		{"lowercase scheme", "bearer " + token, fiber.StatusOK, "", "user-42"},
+		{"missing header", "", fiber.StatusUnauthorized, "Bearer", ""},
+		{"basic auth", "Basic dXNlcjpwYXNz", fiber.StatusUnauthorized, "Bearer", ""},
+		{"invalid token", "Bearer not.a.token", fiber.StatusUnauthorized, `Bearer error="invalid_token"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/whoami", nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, expected %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(fiber.HeaderWWWAuthenticate); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, expected %q", got, tt.wantChallenge)
+			}
+			if tt.wantSubject != "" {
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != tt.wantSubject {
+					t.Errorf("subject = %q, expected %q", body, tt.wantSubject)
+				}
+			}
+		})
+	}
+}
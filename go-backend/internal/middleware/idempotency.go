@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"user-api/internal/apperror"
+	"user-api/internal/logger"
+	"user-api/internal/models"
+)
+
+// Idempotency headers
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength bounds the stored key
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are stored with a response and sent again on replay
+var replayedHeaders = []string{fiber.HeaderContentType, fiber.HeaderLocation, fiber.HeaderETag}
+
+// IdempotencyStore persists the outcome of requests by idempotency key
+type IdempotencyStore interface {
+	Claim(ctx context.Context, scope, key string, fingerprint []byte, expiresAt time.Time) (*models.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe
+// to retry. The first request with a key runs normally and its response is
+// stored for ttl; retries with the same key and body get the stored
+// response with Idempotent-Replayed: true. Reusing a key with a different
+// request gets 422, and a retry while the original is still running gets
+// 409. Keys are scoped to the caller and route, and the header is ignored
+// for anonymous callers, who cannot be told apart. Server errors, rate limit
+// and authorization failures are not stored so the request can be retried.
+// Responses marked Cache-Control: no-store, such as minted API keys, are
+// never stored either, so secrets do not outlive the response.
+func Idempotency(store IdempotencyStore, ttl time.Duration, log *logger.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if key == "" || c.Method() != fiber.MethodPost || Subject(c) == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return apperror.Respond(c, apperror.New(apperror.CodeInvalidRequest, "Idempotency-Key must be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters"))
+		}
+
+		ctx := c.Context()
+		scope := idempotencyScope(c)
+		fingerprint := requestFingerprint(c)
+
+		record, claimed, err := store.Claim(ctx, scope, key, fingerprint, time.Now().Add(ttl))
+		if err != nil {
+			log.FromContext(ctx).Error("Failed to claim idempotency key", zap.Error(err))
+			return apperror.Respond(c, apperror.Wrap(apperror.CodeInternal, "Failed to process Idempotency-Key", err))
+		}
+
+		if !claimed {
+			if !bytes.Equal(record.Fingerprint, fingerprint) {
+				return apperror.Respond(c, apperror.New(apperror.CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request"))
+			}
+			if !record.Completed() {
+				c.Set(fiber.HeaderRetryAfter, "1")
+				return apperror.Respond(c, apperror.New(apperror.CodeIdempotencyInProgress, "A request with this Idempotency-Key is still in progress"))
+			}
+
+			log.FromContext(ctx).Info("Replaying idempotent response", zap.Int("status", record.StatusCode))
+			for name, value := range record.Headers {
+				c.Set(name, value)
+			}
+			c.Set(HeaderIdempotentReplayed, "true")
+			return c.Status(record.StatusCode).Send(record.Body)
+		}
+
+		// Errors returned by handlers are rendered by the error handler
+		// after this middleware, so their responses are never stored
+		if err := c.Next(); err != nil {
+			releaseIdempotencyKey(c, store, scope, key, log)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if !storableStatus(status) || noStore(c.GetRespHeader(fiber.HeaderCacheControl)) {
+			releaseIdempotencyKey(c, store, scope, key, log)
+			return nil
+		}
+
+		headers := make(map[string]string, len(replayedHeaders))
+		for _, name := range replayedHeaders {
+			if value := c.GetRespHeader(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if err := store.Complete(ctx, scope, key, status, headers, c.Response().Body()); err != nil {
+			log.FromContext(ctx).Error("Failed to store idempotent response", zap.Error(err))
+			releaseIdempotencyKey(c, store, scope, key, log)
+		}
+		return nil
+	}
+}
+
+// idempotencyScope keeps keys of different callers and routes apart
+func idempotencyScope(c *fiber.Ctx) string {
+	return "subject:" + Subject(c) + " " + c.Method() + " " + c.Path()
+}
+
+// requestFingerprint hashes the parts of a request a retry must repeat
+func requestFingerprint(c *fiber.Ctx) []byte {
+	h := sha256.New()
+	h.Write(c.Request().URI().QueryString())
+	h.Write([]byte{0})
+	h.Write([]byte(c.Get(fiber.HeaderContentType)))
+	h.Write([]byte{0})
+	h.Write(c.Body())
+	return h.Sum(nil)
+}
+
+// storableStatus reports whether a response is final for its request.
+// Server errors and rejections that a retry may get past are not.
+func storableStatus(status int) bool {
+	switch status {
+	case fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests:
+		return false
+	}
+	return status < fiber.StatusInternalServerError
+}
+
+// noStore reports whether a Cache-Control value forbids storing the response
+func noStore(cacheControl string) bool {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+func releaseIdempotencyKey(c *fiber.Ctx, store IdempotencyStore, scope, key string, log *logger.Logger) {
+	if err := store.Release(c.Context(), scope, key); err != nil {
+		log.FromContext(c.Context()).Error("Failed to release idempotency key", zap.Error(err))
+	}
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user-api/internal/logger"
+	"user-api/internal/models"
+)
+
+// memoryIdempotencyStore is an in-memory IdempotencyStore
+type memoryIdempotencyStore struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+func (s *memoryIdempotencyStore) Claim(ctx context.Context, scope, key string, fingerprint []byte, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	if record, ok := s.records[scope+"|"+key]; ok && record.ExpiresAt.After(time.Now()) {
+		return record, false, nil
+	}
+	record := &models.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint, ExpiresAt: expiresAt}
+	s.records[scope+"|"+key] = record
+	return record, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error {
+	record := s.records[scope+"|"+key]
+	record.StatusCode = statusCode
+	record.Headers = headers
+	record.Body = append([]byte(nil), body...)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	delete(s.records, scope+"|"+key)
+	return nil
+}
+
+// testSubject authenticates requests as the subject in their X-Subject
+// header, or leaves them anonymous without one
+func testSubject(c *fiber.Ctx) error {
+	if subject := c.Get("X-Subject"); subject != "" {
+		c.Locals(LocalsSubject, subject)
+	}
+	return c.Next()
+}
+
+func TestIdempotency(t *testing.T) {
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+	created := 0
+	failures := 1
+
+	app := fiber.New()
+	app.Use(testSubject, Idempotency(store, time.Hour, logger.NewLogger()))
+	app.Post("/users", func(c *fiber.Ctx) error {
+		created++
+		c.Set(fiber.HeaderETag, `"v1"`)
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": created})
+	})
+	app.Post("/flaky", func(c *fiber.Ctx) error {
+		if failures > 0 {
+			failures--
+			return c.SendStatus(fiber.StatusServiceUnavailable)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	do := func(path, key, body string) (*http.Response, string) {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set("X-Subject", "alice")
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	first, firstBody := do("/users", "key-1", `{"name":"Alice"}`)
+	if first.StatusCode != fiber.StatusCreated || first.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Fatalf("first request status = %d, headers %v", first.StatusCode, first.Header)
+	}
+
+	retry, retryBody := do("/users", "key-1", `{"name":"Alice"}`)
+	if retry.StatusCode != fiber.StatusCreated || retryBody != firstBody || created != 1 {
+		t.Errorf("retry = %d %s after %d creates, expected the stored response", retry.StatusCode, retryBody, created)
+	}
+	if retry.Header.Get(HeaderIdempotentReplayed) != "true" || retry.Header.Get(fiber.HeaderETag) != `"v1"` {
+		t.Errorf("retry headers = %v, expected replay marker and stored ETag", retry.Header)
+	}
+
+	if resp, _ := do("/users", "key-1", `{"name":"Bob"}`); resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Errorf("reuse with a different body status = %d, expected %d", resp.StatusCode, fiber.StatusUnprocessableEntity)
+	}
+
+	if _, body := do("/users", "", `{"name":"Alice"}`); body != `{"id":2}` {
+		t.Errorf("request without a key = %s, expected a new user", body)
+	}
+
+	// Server errors are not stored, so a retry runs again
+	if resp, _ := do("/flaky", "key-2", ""); resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Fatalf("flaky status = %d, expected %d", resp.StatusCode, fiber.StatusServiceUnavailable)
+	}
+	if resp, _ := do("/flaky", "key-2", ""); resp.StatusCode != fiber.StatusOK || resp.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Errorf("flaky retry status = %d, expected a fresh %d", resp.StatusCode, fiber.StatusOK)
+	}
+
+	if resp, _ := do("/users", strings.Repeat("k", maxIdempotencyKeyLength+1), ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("long key status = %d, expected %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+}
+
+func TestIdempotency_NoStore(t *testing.T) {
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+	minted := 0
+	app := fiber.New()
+	app.Use(testSubject, Idempotency(store, time.Hour, logger.NewLogger()))
+	app.Post("/api-keys", func(c *fiber.Ctx) error {
+		minted++
+		c.Set(fiber.HeaderCacheControl, "private, no-store")
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"key": "uak_secret_" + strconv.Itoa(minted)})
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(fiber.MethodPost, "/api-keys", strings.NewReader(`{"name":"ci"}`))
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		req.Header.Set("X-Subject", "alice")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		if resp.StatusCode != fiber.StatusCreated || resp.Header.Get(HeaderIdempotentReplayed) != "" {
+			t.Errorf("request %d status = %d, headers %v, expected a fresh %d", i+1, resp.StatusCode, resp.Header, fiber.StatusCreated)
+		}
+		if len(store.records) != 0 {
+			t.Fatalf("store = %v after request %d, expected the minted key never to be stored", store.records, i+1)
+		}
+	}
+	if minted != 2 {
+		t.Errorf("minted %d keys, expected each request to run", minted)
+	}
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+	app := fiber.New()
+	app.Use(testSubject, Idempotency(store, time.Hour, logger.NewLogger()))
+	app.Post("/users", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+
+	// A claim without a stored response is still in progress
+	if _, _, err := store.Claim(context.Background(), "subject:alice POST /users", "key-1", requestFingerprintOf(t), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	req := httptest.NewRequest(fiber.MethodPost, "/users", nil)
+	req.Header.Set(HeaderIdempotencyKey, "key-1")
+	req.Header.Set("X-Subject", "alice")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	if resp.StatusCode != fiber.StatusConflict || resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Errorf("status = %d, expected %d with Retry-After", resp.StatusCode, fiber.StatusConflict)
+	}
+}
+
+func TestIdempotency_Callers(t *testing.T) {
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+	created := 0
+	app := fiber.New()
+	app.Use(testSubject, Idempotency(store, time.Hour, logger.NewLogger()))
+	app.Post("/users", func(c *fiber.Ctx) error {
+		created++
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": created, "by": Subject(c)})
+	})
+
+	do := func(subject string) (*http.Response, string) {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodPost, "/users", strings.NewReader(`{"name":"Alice"}`))
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		if subject != "" {
+			req.Header.Set("X-Subject", subject)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	// Callers sending the same key never see each other's responses
+	if _, body := do("alice"); body != `{"by":"alice","id":1}` {
+		t.Errorf("alice = %s, expected a new user", body)
+	}
+	if resp, body := do("bob"); body != `{"by":"bob","id":2}` || resp.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Errorf("bob = %s, expected a new user rather than alice's response", body)
+	}
+
+	// Anonymous callers cannot be told apart, so their keys are ignored
+	for i := 3; i <= 4; i++ {
+		resp, body := do("")
+		if body != `{"by":"","id":`+strconv.Itoa(i)+`}` || resp.Header.Get(HeaderIdempotentReplayed) != "" {
+			t.Errorf("anonymous request = %s, expected a new user", body)
+		}
+	}
+	if len(store.records) != 2 {
+		t.Errorf("store has %d records, expected only the authenticated callers'", len(store.records))
+	}
+}
+
+// requestFingerprintOf returns the fingerprint of an empty POST /users
+func requestFingerprintOf(t *testing.T) []byte {
+	t.Helper()
+	var fingerprint []byte
+	probe := fiber.New()
+	probe.Post("/users", func(c *fiber.Ctx) error {
+		fingerprint = requestFingerprint(c)
+		return nil
+	})
+	if _, err := probe.Test(httptest.NewRequest(fiber.MethodPost, "/users", nil)); err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	return fingerprint
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user-api/internal/metrics"
+)
+
+// routeUnmatched labels requests that matched no route
+const routeUnmatched = "unmatched"
+
+// Metrics records the count and latency of every request, labelled with
+// the route template rather than the raw path so that IDs do not create
+// new series
+func Metrics(m *metrics.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		route, status := requestOutcome(c, err)
+		m.ObserveRequest(c.Method(), route, status, time.Since(start))
+		return err
+	}
+}
+
+// requestOutcome returns the route template that handled a request and
+// the status it will be answered with once err, if any, is handled
+func requestOutcome(c *fiber.Ctx, err error) (string, int) {
+	// Escaped colons, as in /users\:export, are literal
+	route := strings.ReplaceAll(c.Route().Path, "\\:", ":")
+	status := c.Response().StatusCode()
+	if err != nil {
+		// The error handler writes the response after this returns
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+			// Handlers answer with c.Status, so a 404 or 405 error comes
+			// from the router finding no route
+			if status == fiber.StatusNotFound || status == fiber.StatusMethodNotAllowed {
+				route = routeUnmatched
+			}
+		}
+	}
+	return route, status
+}
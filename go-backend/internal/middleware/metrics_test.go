@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	"user-api/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	app := fiber.New()
+	app.Use(Metrics(m))
+	app.Get("/metrics", adaptor.HTTPHandler(m.Handler()))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("id"))
+	})
+	app.Get("/users\\:export", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/users:export", "/missing"} {
+		if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil)); err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/metrics", nil))
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`http_requests_total{method="GET",route="/users:export",status="204"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 2`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %s", want)
+		}
+	}
+}
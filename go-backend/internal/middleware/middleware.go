@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"user-api/internal/apperror"
 	"user-api/internal/logger"
 )
 
@@ -23,11 +24,14 @@ func RequestID() fiber.Handler {
 	}
 }
 
-// LoggerMiddleware logs incoming requests and their responses
+// LoggerMiddleware logs incoming requests and their responses. It also
+// stores a request-scoped logger carrying the request ID in c.Locals, which
+// later middleware can enrich, e.g. with the caller identity.
 func LoggerMiddleware(log *logger.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
 		requestID := c.Locals("requestID").(string)
+		c.Locals(logger.LocalsKey, log.WithRequestID(requestID))
 
 		// Log incoming request
 		log.Info("Incoming request",
@@ -41,10 +45,9 @@ func LoggerMiddleware(log *logger.Logger) fiber.Handler {
 		// Process request
 		err := c.Next()
 
-		// Log response
+		// Log response with the request logger, which now knows the caller
 		duration := time.Since(start)
-		log.Info("Request completed",
-			zap.String("request_id", requestID),
+		log.FromContext(c.Context()).Info("Request completed",
 			zap.Int("status", c.Response().StatusCode()),
 			zap.Duration("duration", duration),
 		)
@@ -53,16 +56,30 @@ func LoggerMiddleware(log *logger.Logger) fiber.Handler {
 	}
 }
 
-// ErrorHandler is a custom error handler for Fiber
-func ErrorHandler(c *fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
-
-	// Check if it's a Fiber error
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
+// RequireIfMatch rejects PUT, PATCH and DELETE requests that do not carry an
+// If-Match header with 428 Precondition Required, so clients cannot
+// overwrite changes they have not seen
+func RequireIfMatch() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+			if c.Get(fiber.HeaderIfMatch) == "" {
+				return apperror.Respond(c, apperror.New(apperror.CodePreconditionRequired, "If-Match header is required"))
+			}
+		}
+		return c.Next()
 	}
+}
 
-	return c.Status(code).JSON(fiber.Map{
-		"error": err.Error(),
-	})
+// NewErrorHandler renders errors that reach Fiber, such as unmatched routes
+// and panics caught by the recover middleware, as problem documents.
+// Internal errors are logged; their message is never sent to the client.
+func NewErrorHandler(log *logger.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		appErr := apperror.From(err)
+		if appErr.Status >= fiber.StatusInternalServerError {
+			log.FromContext(c.Context()).Error("Request failed", zap.Error(err))
+		}
+		return apperror.Respond(c, appErr)
+	}
 }
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+
+	"user-api/internal/apperror"
+	"user-api/internal/logger"
+)
+
+func TestNewErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(logger.NewLogger())})
+	app.Use(recover.New())
+	app.Use(RequestID())
+	app.Get("/users", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("database password is hunter2")
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantCode   apperror.Code
+	}{
+		{"unmatched route", fiber.MethodGet, "/missing", fiber.StatusNotFound, apperror.CodeNotFound},
+		{"wrong method", fiber.MethodDelete, "/users", fiber.StatusMethodNotAllowed, apperror.CodeMethodNotAllowed},
+		{"panic", fiber.MethodGet, "/panic", fiber.StatusInternalServerError, apperror.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, expected %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(fiber.HeaderContentType); got != apperror.ContentType {
+				t.Errorf("Content-Type = %q, expected %q", got, apperror.ContentType)
+			}
+
+			var body map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if body["code"] != string(tt.wantCode) || body["request_id"] == "" || body["request_id"] != resp.Header.Get(fiber.HeaderXRequestID) {
+				t.Errorf("body = %v, expected code %s with the request ID", body, tt.wantCode)
+			}
+			if detail, _ := body["detail"].(string); strings.Contains(detail, "hunter2") {
+				t.Errorf("detail %q leaks the panic value", detail)
+			}
+		})
+	}
+}
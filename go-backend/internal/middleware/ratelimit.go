@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"user-api/internal/apperror"
+	"user-api/internal/logger"
+	"user-api/internal/ratelimit"
+)
+
+// Rate limit response headers
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// RateLimiter applies per-route token-bucket limits to each client
+type RateLimiter struct {
+	store        ratelimit.Store
+	defaultLimit ratelimit.Limit
+	routes       map[string]ratelimit.Limit
+	logger       *logger.Logger
+}
+
+// NewRateLimiter creates a RateLimiter keeping buckets in store. Routes
+// without an entry in routes use defaultLimit.
+func NewRateLimiter(store ratelimit.Store, defaultLimit ratelimit.Limit, routes map[string]ratelimit.Limit, logger *logger.Logger) *RateLimiter {
+	return &RateLimiter{
+		store:        store,
+		defaultLimit: defaultLimit,
+		routes:       routes,
+		logger:       logger,
+	}
+}
+
+// Limit returns a handler enforcing the limit configured for the named
+// route. Each client gets its own bucket per route, keyed by API key, token
+// subject or IP address in that order. Responses carry RateLimit-* headers
+// and rejected requests get 429 with Retry-After. If the store fails the
+// request is let through. A nil RateLimiter allows every request.
+func (l *RateLimiter) Limit(route string) fiber.Handler {
+	if l == nil {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+
+	limit, ok := l.routes[route]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	if limit.Unlimited() {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	policy := strconv.Itoa(limit.Requests) + ";w=" + strconv.Itoa(ceilSeconds(limit.Period))
+
+	return func(c *fiber.Ctx) error {
+		client := clientKey(c)
+		res, err := l.store.Take(c.Context(), route+"|"+client, limit)
+		if err != nil {
+			l.logger.FromContext(c.Context()).Error("Rate limit store failed", zap.String("route", route), zap.Error(err))
+			return c.Next()
+		}
+
+		c.Set(HeaderRateLimitLimit, strconv.Itoa(limit.Requests))
+		c.Set(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+		c.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(res.Reset)))
+		c.Set(HeaderRateLimitPolicy, policy)
+
+		if !res.Allowed {
+			l.logger.FromContext(c.Context()).Warn("Rate limit exceeded",
+				zap.String("route", route),
+				zap.String("client", client),
+			)
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			return apperror.Respond(c, apperror.New(apperror.CodeRateLimited, "Rate limit exceeded for this route, retry after the Retry-After delay"))
+		}
+
+		return c.Next()
+	}
+}
+
+// clientKey identifies the caller for rate limiting. API key callers carry
+// an "api_key:" subject, so they never share a bucket with token subjects.
+func clientKey(c *fiber.Ctx) string {
+	if subject := Subject(c); subject != "" {
+		return "subject:" + subject
+	}
+	return "ip:" + c.IP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
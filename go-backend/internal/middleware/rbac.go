@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+
+	"user-api/internal/apperror"
+	"user-api/internal/logger"
+	"user-api/internal/rbac"
+)
+
+// Reasons reported in the body of 403 responses
+const (
+	ReasonNoRole            = "no_role"
+	ReasonMissingPermission = "missing_permission"
+	ReasonMissingScope      = "missing_scope"
+	// ReasonRBACDisabled rejects routes that are closed while role-based
+	// access control is disabled
+	ReasonRBACDisabled = "rbac_disabled"
+)
+
+// RoleSource extracts the caller's roles from a request
+type RoleSource func(c *fiber.Ctx) []string
+
+// RolesFromHeader reads comma-separated roles from a header. The header must
+// be set by a trusted proxy that strips any client-supplied value.
+func RolesFromHeader(name string) RoleSource {
+	return func(c *fiber.Ctx) []string {
+		return splitRoles(c.Get(name))
+	}
+}
+
+// RolesFromClaim reads roles from a claim of the verified token, given
+// either as a list or as a space or comma separated string
+func RolesFromClaim(name string) RoleSource {
+	return func(c *fiber.Ctx) []string {
+		claims, ok := c.Locals(LocalsClaims).(jwt.MapClaims)
+		if !ok {
+			return nil
+		}
+
+		switch value := claims[name].(type) {
+		case string:
+			return splitRoles(value)
+		case []interface{}:
+			var roles []string
+			for _, v := range value {
+				if role, ok := v.(string); ok && role != "" {
+					roles = append(roles, role)
+				}
+			}
+			return roles
+		}
+		return nil
+	}
+}
+
+func splitRoles(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// Authorizer enforces a Policy on individual routes
+type Authorizer struct {
+	policy *rbac.Policy
+	roles  RoleSource
+	logger *logger.Logger
+}
+
+// NewAuthorizer creates an Authorizer checking the roles from source
+// against policy
+func NewAuthorizer(policy *rbac.Policy, source RoleSource, logger *logger.Logger) *Authorizer {
+	return &Authorizer{
+		policy: policy,
+		roles:  source,
+		logger: logger,
+	}
+}
+
+// Require returns a handler rejecting callers whose roles do not grant the
+// permission with 403 and a machine-readable reason. Callers authenticated
+// by an API key are checked against the key's scopes instead of roles. A
+// nil Authorizer only checks API key scopes and otherwise allows every
+// request, so routes can be declared the same way whether or not role-based
+// access control is enabled.
+func (a *Authorizer) Require(permission string) fiber.Handler {
+	return a.require(permission, false)
+}
+
+// RequireStrict is like Require, but a nil Authorizer only admits callers
+// whose API key holds the permission. It guards routes that must stay
+// closed unless role-based access control is enabled.
+func (a *Authorizer) RequireStrict(permission string) fiber.Handler {
+	return a.require(permission, true)
+}
+
+func (a *Authorizer) require(permission string, strict bool) fiber.Handler {
+	var log *logger.Logger
+	if a != nil {
+		log = a.logger
+	}
+
+	return func(c *fiber.Ctx) error {
+		if scopes, ok := c.Locals(LocalsScopes).([]string); ok {
+			if hasScope(scopes, permission) {
+				return c.Next()
+			}
+			return forbidden(c, log, ReasonMissingScope, permission, zap.Strings("scopes", scopes))
+		}
+		if a == nil {
+			if strict {
+				return forbidden(c, log, ReasonRBACDisabled, permission)
+			}
+			return c.Next()
+		}
+
+		roles := a.roles(c)
+		if a.policy.Allows(roles, permission) {
+			return c.Next()
+		}
+
+		reason := ReasonMissingPermission
+		if len(roles) == 0 {
+			reason = ReasonNoRole
+		}
+		return forbidden(c, a.logger, reason, permission, zap.Strings("roles", roles))
+	}
+}
+
+// Holds reports whether the caller of a request holds the permission, by
+// its API key scopes or its roles. Like Require, a nil Authorizer grants
+// every permission to callers without an API key.
+func (a *Authorizer) Holds(c *fiber.Ctx, permission string) bool {
+	if scopes, ok := c.Locals(LocalsScopes).([]string); ok {
+		return hasScope(scopes, permission)
+	}
+	return a == nil || a.policy.Allows(a.roles(c), permission)
+}
+
+func hasScope(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// forbidden logs a denied request and answers 403 with the reason
+func forbidden(c *fiber.Ctx, log *logger.Logger, reason, permission string, fields ...zap.Field) error {
+	if log := log.FromContext(c.Context()); log != nil {
+		log.Warn("Permission denied", append(fields,
+			zap.String("permission", permission),
+			zap.String("reason", reason),
+		)...)
+	}
+
+	return apperror.Respond(c, apperror.New(apperror.CodeForbidden, "Missing permission "+permission).
+		With("reason", reason).
+		With("permission", permission))
+}
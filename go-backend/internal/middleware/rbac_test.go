@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRolesFromClaim(t *testing.T) {
+	app := fiber.New()
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   []string
+	}{
+		{"list", jwt.MapClaims{"roles": []interface{}{"viewer", "editor"}}, []string{"viewer", "editor"}},
+		{"space separated", jwt.MapClaims{"roles": "viewer editor"}, []string{"viewer", "editor"}},
+		{"missing claim", jwt.MapClaims{"sub": "user-42"}, nil},
+		{"unauthenticated", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			if tt.claims != nil {
+				c.Locals(LocalsClaims, tt.claims)
+			}
+
+			got := RolesFromClaim("roles")(c)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("RolesFromClaim() = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+// Package migrate applies the embedded schema migrations and records every
+// applied version with its checksum in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"user-api/internal/logger"
+)
+
+var (
+	ErrInvalidFilename  = errors.New("invalid migration filename")
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrNoDownMigration  = errors.New("migration has no down script")
+)
+
+// lockID is the Postgres advisory lock key serialising migration runs
+// across processes
+const lockID int64 = 0x75736572617069 // "userapi"
+
+const createTableSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)
+`
+
+var filenamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single schema version
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of the up script
+	Checksum string
+}
+
+// Status describes a known migration and whether it has been applied
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set when the applied checksum differs from the embedded file
+	Modified bool `json:"modified,omitempty"`
+}
+
+// applied is a row of schema_migrations
+type applied struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// Load reads the migrations in the root of fsys, ordered by version.
+// Files that are not SQL are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := filenamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFilename, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFilename, entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: %d has no up script", ErrInvalidFilename, m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations against a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *logger.Logger
+}
+
+// New creates a Migrator for the migrations in fsys
+func New(db *sql.DB, fsys fs.FS, logger *logger.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// Latest returns the highest known version, or 0 when there are none
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down reverts the given number of most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(state); err != nil {
+			return err
+		}
+
+		versions := appliedVersions(state)
+		if steps > len(versions) {
+			steps = len(versions)
+		}
+
+		var target int64
+		if steps < len(versions) {
+			target = versions[len(versions)-steps-1]
+		}
+
+		count, err = m.migrate(ctx, conn, state, target)
+		return err
+	})
+	return count, err
+}
+
+// Goto migrates up or down until exactly the migrations up to and including
+// version are applied. Version 0 reverts everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) (int, error) {
+	if version != 0 && m.find(version) == nil {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(state); err != nil {
+			return err
+		}
+
+		count, err = m.migrate(ctx, conn, state, version)
+		return err
+	})
+	return count, err
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for version := range state {
+			if m.find(version) == nil {
+				return fmt.Errorf("%w: %d is applied but not embedded", ErrUnknownVersion, version)
+			}
+		}
+
+		statuses = make([]Status, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if a, ok := state[migration.Version]; ok {
+				appliedAt := a.appliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+				status.Modified = a.checksum != migration.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Version returns the highest applied version, or 0 when none is applied
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return CurrentVersion(ctx, m.db)
+}
+
+// CurrentVersion returns the highest version recorded in schema_migrations,
+// or 0 when the table is missing or empty
+func CurrentVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version.Int64, nil
+}
+
+// migrate applies or reverts migrations until target is the latest applied
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, state map[int64]applied, target int64) (int, error) {
+	steps, err := plan(m.migrations, state, target)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, step := range steps {
+		if err := m.run(ctx, conn, step); err != nil {
+			return i, err
+		}
+	}
+	return len(steps), nil
+}
+
+// run executes a single step and records it in one transaction
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, step step) error {
+	direction := "up"
+	if step.down {
+		direction = "down"
+	}
+	fields := []zap.Field{
+		zap.Int64("version", step.migration.Version),
+		zap.String("name", step.migration.Name),
+		zap.String("direction", direction),
+	}
+	m.logger.Info("Applying migration", fields...)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script := step.migration.Up
+	if step.down {
+		script = step.migration.Down
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		m.logger.Error("Migration failed", append(fields, zap.Error(err))...)
+		return fmt.Errorf("migration %d_%s %s: %w", step.migration.Version, step.migration.Name, direction, err)
+	}
+
+	if step.down {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, step.migration.Version)
+	} else {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			step.migration.Version, step.migration.Name, step.migration.Checksum)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// verify checks that every applied migration is still embedded unchanged
+func (m *Migrator) verify(state map[int64]applied) error {
+	for version, a := range state {
+		migration := m.find(version)
+		if migration == nil {
+			return fmt.Errorf("%w: %d is applied but not embedded", ErrUnknownVersion, version)
+		}
+		if migration.Checksum != a.checksum {
+			return fmt.Errorf("%w: %d_%s was modified after it was applied", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory
+// lock, creating schema_migrations first if needed
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock even when ctx is done so the session does not keep the lock
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			m.logger.Error("Failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTableSQL); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	state := make(map[int64]applied)
+	for rows.Next() {
+		var version int64
+		var a applied
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		state[version] = a
+	}
+
+	return state, rows.Err()
+}
+
+func appliedVersions(state map[int64]applied) []int64 {
+	versions := make([]int64, 0, len(state))
+	for version := range state {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// step is a single migration to apply or revert
+type step struct {
+	migration *Migration
+	down      bool
+}
+
+// plan lists the steps needed so that exactly the migrations up to target
+// are applied: applied ones above target are reverted newest first, then
+// pending ones up to target, including gaps, are applied oldest first.
+func plan(migrations []Migration, state map[int64]applied, target int64) ([]step, error) {
+	var up, down []step
+	for i := range migrations {
+		migration := &migrations[i]
+		_, isApplied := state[migration.Version]
+
+		switch {
+		case migration.Version <= target && !isApplied:
+			up = append(up, step{migration: migration})
+		case migration.Version > target && isApplied:
+			if migration.Down == "" {
+				return nil, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+			}
+			down = append([]step{{migration: migration, down: true}}, down...)
+		}
+	}
+
+	return append(down, up...), nil
+}
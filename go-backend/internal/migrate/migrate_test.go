@@ -0,0 +1,159 @@
+package migrate
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"user-api/db/migrations"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"001_init.up.sql":    {Data: []byte("CREATE TABLE a (id INT);")},
+		"001_init.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"002_more.up.sql":    {Data: []byte("CREATE TABLE b (id INT);")},
+		"003_no_down.up.sql": {Data: []byte("CREATE TABLE c (id INT);")},
+		"migrations.go":      {Data: []byte("package migrations")},
+		"002_more.down.sql":  {Data: []byte("DROP TABLE b;")},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	loaded, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(loaded) != 3 {
+		t.Fatalf("Load() returned %d migrations, expected 3", len(loaded))
+	}
+	for i, version := range []int64{1, 2, 3} {
+		if loaded[i].Version != version {
+			t.Errorf("migration %d has version %d, expected %d", i, loaded[i].Version, version)
+		}
+	}
+	if loaded[1].Name != "more" || loaded[1].Down != "DROP TABLE b;" {
+		t.Errorf("Unexpected migration %+v", loaded[1])
+	}
+	if loaded[0].Checksum == "" || loaded[0].Checksum == loaded[1].Checksum {
+		t.Errorf("Expected distinct checksums, got %q and %q", loaded[0].Checksum, loaded[1].Checksum)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"bad name", fstest.MapFS{"init.sql": {Data: []byte("SELECT 1")}}},
+		{"down only", fstest.MapFS{"001_init.down.sql": {Data: []byte("SELECT 1")}}},
+		{"duplicate version", fstest.MapFS{
+			"001_a.up.sql": {Data: []byte("SELECT 1")},
+			"001_b.up.sql": {Data: []byte("SELECT 2")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); err == nil {
+				t.Error("Load() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load(migrations.FS) error = %v", err)
+	}
+	for _, m := range loaded {
+		if m.Down == "" {
+			t.Errorf("Embedded migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	loaded, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		applied []int64
+		target  int64
+		want    []string
+	}{
+		{"up from empty", nil, 3, []string{"up 1", "up 2", "up 3"}},
+		{"up to target", nil, 2, []string{"up 1", "up 2"}},
+		{"fills gaps", []int64{2}, 2, []string{"up 1"}},
+		{"down to target", []int64{1, 2}, 1, []string{"down 2"}},
+		{"down to zero", []int64{1, 2}, 0, []string{"down 2", "down 1"}},
+		{"nothing to do", []int64{1, 2}, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := make(map[int64]applied)
+			for _, version := range tt.applied {
+				state[version] = applied{}
+			}
+
+			steps, err := plan(loaded, state, tt.target)
+			if err != nil {
+				t.Fatalf("plan() error = %v", err)
+			}
+
+			var got []string
+			for _, s := range steps {
+				direction := "up"
+				if s.down {
+					direction = "down"
+				}
+				got = append(got, direction+" "+strconv.FormatInt(s.migration.Version, 10))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("plan() = %v, expected %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("plan() = %v, expected %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPlan_NoDownMigration(t *testing.T) {
+	loaded, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	state := map[int64]applied{1: {}, 2: {}, 3: {}}
+	if _, err := plan(loaded, state, 2); !errors.Is(err, ErrNoDownMigration) {
+		t.Errorf("plan() error = %v, expected ErrNoDownMigration", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	m, err := New(nil, testFS(), nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := m.verify(map[int64]applied{1: {checksum: m.migrations[0].Checksum}}); err != nil {
+		t.Errorf("verify() error = %v", err)
+	}
+	if err := m.verify(map[int64]applied{1: {checksum: "changed"}}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("verify() error = %v, expected ErrChecksumMismatch", err)
+	}
+	if err := m.verify(map[int64]applied{9: {}}); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("verify() error = %v, expected ErrUnknownVersion", err)
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// APIKey represents a stored API key. The secret itself is never stored,
+// only its salted hash.
+type APIKey struct {
+	ID         int64
+	Name       string
+	Prefix     string
+	Salt       []byte
+	Hash       []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// CreateAPIKeyRequest represents the request body for minting an API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,min=1,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write users:delete api_keys:manage"`
+}
+
+// APIKeyResponse represents an API key in responses, without its secret
+type APIKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreatedAPIKeyResponse is returned once when a key is minted or rotated;
+// Key is the only time the plaintext key is revealed
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// ToResponse converts an APIKey to an APIKeyResponse
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+// Active reports whether the key can still be used
+func (k *APIKey) Active() bool {
+	return k.RevokedAt == nil
+}
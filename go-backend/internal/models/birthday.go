@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// DefaultBirthdayWindow is the number of days ahead searched for birthdays
+// when neither within_days nor a date range is given
+const DefaultBirthdayWindow = 30
+
+// MaxBirthdayWindow is the largest number of days a birthday search may
+// span after its first day. Both ends are inclusive, so the longest search
+// covers 366 dates and can hold two birthdays of the same user in a common
+// year; users are listed once, at the first of them.
+const MaxBirthdayWindow = 365
+
+// BirthdaysQuery represents the query parameters accepted by the birthdays endpoint
+type BirthdaysQuery struct {
+	Page     int `query:"page"`
+	PageSize int `query:"page_size"`
+	// WithinDays searches from today up to this many days ahead
+	WithinDays *int `query:"within_days" validate:"omitnil,min=0,max=365"`
+	// From and To search an explicit range of dates instead, both inclusive
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	// TZ is the IANA time zone whose current date within_days counts from
+	TZ string `query:"tz"`
+}
+
+// BirthdayListOptions represents the options used by the repository to list
+// upcoming birthdays
+type BirthdayListOptions struct {
+	// From and To bound the birthdays, both inclusive
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// UpcomingBirthday is a user with their next birthday on or after the start
+// of a search
+type UpcomingBirthday struct {
+	User     *User
+	Birthday time.Time
+}
+
+// BirthdayResponse represents an upcoming birthday in API responses
+type BirthdayResponse struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	DOB      string `json:"dob"`
+	Birthday string `json:"birthday"`
+	// Turns is the age reached on the birthday
+	Turns int `json:"turns"`
+}
+
+// BirthdaysResponse represents a page of upcoming birthdays together with
+// the range of dates searched
+type BirthdaysResponse struct {
+	PaginatedResponse
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NextBirthday returns the first birthday of someone born on dob that falls
+// on or after the date of from, following the Birthday rules. The day of
+// birth is not a birthday; for people born after from the result is not
+// after dob.
+func NextBirthday(dob, from time.Time) time.Time {
+	year, month, day := from.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if birthday := Birthday(dob, year); !birthday.Before(start) && birthday.After(dob) {
+		return birthday
+	}
+	return Birthday(dob, year+1)
+}
+
+// ToResponse converts an UpcomingBirthday to a BirthdayResponse
+func (b *UpcomingBirthday) ToResponse() BirthdayResponse {
+	return BirthdayResponse{
+		ID:       b.User.ID,
+		Name:     b.User.Name,
+		DOB:      b.User.DOB.Format("2006-01-02"),
+		Birthday: b.Birthday.Format("2006-01-02"),
+		Turns:    b.Birthday.Year() - b.User.DOB.Year(),
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key header
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	Fingerprint []byte
+	// StatusCode is 0 while the original request is still in progress
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Completed reports whether the original request has finished and its
+// response can be replayed
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// ImportMode controls how an import treats invalid rows
+type ImportMode string
+
+const (
+	// ImportModeAtomic inserts nothing unless every row is valid
+	ImportModeAtomic ImportMode = "atomic"
+	// ImportModeBestEffort inserts valid rows and rejects the rest
+	ImportModeBestEffort ImportMode = "best_effort"
+)
+
+// Import row statuses
+const (
+	ImportStatusCreated  = "created"
+	ImportStatusRejected = "rejected"
+	// ImportStatusSkipped marks valid rows not inserted because an atomic
+	// import was rejected
+	ImportStatusSkipped = "skipped"
+)
+
+// ImportRow is an input row prepared for import. Rows with Errors are rejected.
+type ImportRow struct {
+	Row     int
+	Request CreateUserRequest
+	Errors  map[string]string
+}
+
+// ImportRowResult reports the outcome of a single import row
+type ImportRowResult struct {
+	Row    int               `json:"row"`
+	Status string            `json:"status"`
+	ID     int64             `json:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ImportReport represents the response of a bulk import
+type ImportReport struct {
+	Mode     ImportMode        `json:"mode"`
+	Total    int               `json:"total"`
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Results  []ImportRowResult `json:"results"`
+}
+
+// NewUser holds the parsed fields of a user to insert
+type NewUser struct {
+	Name string
+	DOB  time.Time
+}
+
+// BulkCreateResult reports the outcome of inserting a single user in bulk
+type BulkCreateResult struct {
+	ID  int64
+	Err error
+}
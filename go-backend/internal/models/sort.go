@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// SortableUserFields lists the fields users can be sorted by
+var SortableUserFields = []string{"id", "name", "dob", "created_at", "updated_at"}
+
+// SortField represents a single sort key, optionally descending
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// String returns the sort key in query parameter form (e.g. "-dob")
+func (f SortField) String() string {
+	if f.Desc {
+		return "-" + f.Field
+	}
+	return f.Field
+}
+
+// ParseSort parses a comma-separated sort expression such as "name,-dob".
+// Fields prefixed with "-" are sorted descending. Unknown or repeated
+// fields are rejected.
+func ParseSort(raw string) ([]SortField, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var fields []SortField
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		field.Field = strings.TrimPrefix(field.Field, "+")
+
+		if field.Field == "" {
+			return nil, fmt.Errorf("%w: empty sort field", ErrInvalidSort)
+		}
+		if !isSortable(field.Field) {
+			return nil, fmt.Errorf("%w: unknown sort field %q, allowed fields are %s",
+				ErrInvalidSort, field.Field, strings.Join(SortableUserFields, ", "))
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("%w: duplicate sort field %q", ErrInvalidSort, field.Field)
+		}
+
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// FormatSort renders sort fields back into query parameter form
+func FormatSort(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// SortValue returns the user's value for a sortable field in string form
+func (u *User) SortValue(field string) string {
+	switch field {
+	case "name":
+		return u.Name
+	case "dob":
+		return u.DOB.Format("2006-01-02")
+	case "created_at":
+		return u.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		return u.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return strconv.FormatInt(u.ID, 10)
+	}
+}
+
+// ParseSortValue converts a value produced by SortValue back to its typed form
+func ParseSortValue(field, raw string) (interface{}, error) {
+	switch field {
+	case "name":
+		return raw, nil
+	case "dob":
+		return time.Parse("2006-01-02", raw)
+	case "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, raw)
+	case "id":
+		return strconv.ParseInt(raw, 10, 64)
+	default:
+		return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidSort, field)
+	}
+}
+
+func isSortable(field string) bool {
+	for _, f := range SortableUserFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -1,16 +1,22 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
 	"time"
+
+	"user-api/internal/etag"
 )
 
 // User represents the user entity in database
 type User struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	DOB       time.Time `json:"dob"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
-	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	DOB       time.Time  `json:"dob"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // UserResponse represents the API response for a user (includes calculated age)
@@ -19,6 +25,13 @@ type UserResponse struct {
 	Name string `json:"name"`
 	DOB  string `json:"dob"`
 	Age  int    `json:"age,omitempty"`
+	// AgeAsOf is the date the age was calculated on
+	AgeAsOf string `json:"age_as_of,omitempty"`
+	// DeletedAt is only set for soft-deleted users
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// ETag is the entity tag of this representation: the user version it was
+	// built from, plus the reference date when it includes an age
+	ETag string `json:"-"`
 }
 
 // CreateUserRequest represents the request body for creating a user
@@ -39,6 +52,80 @@ type PaginationQuery struct {
 	PageSize int `query:"page_size" validate:"min=1,max=100"`
 }
 
+// PatchUserRequest represents a partial update; nil fields are left unchanged
+type PatchUserRequest struct {
+	Name *string `json:"name,omitempty" validate:"omitnil,min=1,max=100"`
+	DOB  *string `json:"dob,omitempty" validate:"omitnil,datetime=2006-01-02"`
+}
+
+// UserPatch represents the parsed columns to change in a partial update
+type UserPatch struct {
+	Name *string
+	DOB  *time.Time
+}
+
+// ListUsersQuery represents the query parameters accepted by the list endpoint
+type ListUsersQuery struct {
+	Page           int    `query:"page"`
+	PageSize       int    `query:"page_size"`
+	Name           string `query:"name" validate:"omitempty,max=100"`
+	NamePrefix     string `query:"name_prefix" validate:"omitempty,max=100"`
+	DOBFrom        string `query:"dob_from" validate:"omitempty,datetime=2006-01-02"`
+	DOBTo          string `query:"dob_to" validate:"omitempty,datetime=2006-01-02"`
+	MinAge         *int   `query:"min_age" validate:"omitempty,min=0,max=150"`
+	MaxAge         *int   `query:"max_age" validate:"omitempty,min=0,max=150"`
+	Sort           string `query:"sort"`
+	IncludeDeleted bool   `query:"include_deleted"`
+	Cursor         string `query:"cursor"`
+	Limit          int    `query:"limit"`
+	// TZ is the IANA time zone whose current date ages are calculated on
+	TZ string `query:"tz"`
+	// AsOf calculates ages, and applies age filters, on this date instead
+	AsOf string `query:"as_of" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// GetUserQuery represents the query parameters accepted when fetching a user
+type GetUserQuery struct {
+	IncludeDeleted bool `query:"include_deleted"`
+	// TZ is the IANA time zone whose current date the age is calculated on
+	TZ string `query:"tz"`
+	// AsOf calculates the age on this date instead
+	AsOf string `query:"as_of" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// UserFilter represents the parsed filtering options applied to user queries
+type UserFilter struct {
+	NameContains string
+	NamePrefix   string
+	DOBFrom      *time.Time
+	DOBTo        *time.Time
+	MinAge       *int
+	MaxAge       *int
+	// AgeOn is the date MinAge and MaxAge are evaluated on. It is left out
+	// of cursor scopes so cursors stay valid across midnight.
+	AgeOn time.Time `json:"-"`
+	// IncludeDeleted disables the default exclusion of soft-deleted users
+	IncludeDeleted bool
+}
+
+// UserListOptions represents the options used by the repository to list users
+type UserListOptions struct {
+	Filter UserFilter
+	Sort   []SortField
+	Limit  int
+	Offset int
+	// After switches the listing to keyset pagination when set
+	After *Keyset
+}
+
+// Keyset identifies the boundary row of a keyset-paginated page
+type Keyset struct {
+	// Values holds the boundary row's value for each sort field, in order
+	Values   []interface{}
+	ID       int64
+	Backward bool
+}
+
 // PaginatedResponse represents a paginated list response
 type PaginatedResponse struct {
 	Data       interface{} `json:"data"`
@@ -46,32 +133,102 @@ type PaginatedResponse struct {
 	PageSize   int         `json:"page_size"`
 	TotalCount int64       `json:"total_count"`
 	TotalPages int         `json:"total_pages"`
+	Sort       string      `json:"sort,omitempty"`
+}
+
+// CursorPaginatedResponse represents a keyset-paginated list response
+type CursorPaginatedResponse struct {
+	Data       interface{} `json:"data"`
+	Limit      int         `json:"limit"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+	Sort       string      `json:"sort,omitempty"`
 }
 
-// CalculateAge calculates age from date of birth
-func CalculateAge(dob time.Time) int {
-	now := time.Now()
-	years := now.Year() - dob.Year()
+// CalculateAge calculates the age on the calendar date of on, taken in on's
+// location. A birthday counts from the start of its day, and people born on
+// February 29 have their birthday on February 28 in common years.
+func CalculateAge(dob, on time.Time) int {
+	year, month, day := on.Date()
+	age := year - dob.Year()
 
 	// Adjust if birthday hasn't occurred yet this year
-	if now.YearDay() < dob.YearDay() {
-		years--
+	birthday := Birthday(dob, year)
+	if month < birthday.Month() || (month == birthday.Month() && day < birthday.Day()) {
+		age--
+	}
+
+	return age
+}
+
+// Birthday returns the date in year on which the birthday of someone born
+// on dob falls, moving February 29 to February 28 in common years
+func Birthday(dob time.Time, year int) time.Time {
+	month, day := dob.Month(), dob.Day()
+	if month == time.February && day == 29 && !isLeapYear(year) {
+		day = 28
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// LatestDOBForAge returns the latest date of birth of someone who is at
+// least age years old on the date of on, following the CalculateAge rules.
+// Everyone born on or before it has reached that age.
+func LatestDOBForAge(on time.Time, age int) time.Time {
+	year, month, day := on.Date()
+	dobYear := year - age
+	if month == time.February {
+		switch {
+		// Nobody born in a common year has a birthday on February 29
+		case day == 29 && !isLeapYear(dobYear):
+			day = 28
+		// February 29 birthdays fall on February 28 in common years
+		case day == 28 && !isLeapYear(year) && isLeapYear(dobYear):
+			day = 29
+		}
 	}
+	return time.Date(dobYear, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
 
-	return years
+// ETag returns a strong entity tag identifying this version of the user.
+// It changes whenever the row is written, as updated_at is maintained by
+// the update_users_updated_at trigger.
+func (u *User) ETag() string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(u.ID, 10) + ":" + strconv.FormatInt(u.UpdatedAt.UnixMicro(), 10)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
 }
 
-// ToResponse converts User to UserResponse with calculated age
-func (u *User) ToResponse(includeAge bool) UserResponse {
+// ToResponse converts User to UserResponse without an age
+func (u *User) ToResponse() UserResponse {
 	response := UserResponse{
 		ID:   u.ID,
 		Name: u.Name,
 		DOB:  u.DOB.Format("2006-01-02"),
+		ETag: u.ETag(),
 	}
 
-	if includeAge {
-		response.Age = CalculateAge(u.DOB)
+	if u.DeletedAt != nil {
+		deletedAt := u.DeletedAt.UTC()
+		response.DeletedAt = &deletedAt
 	}
 
 	return response
 }
+
+// ToResponseWithAge converts User to UserResponse with the age calculated
+// on the date of today. Users born after today are given no age.
+func (u *User) ToResponseWithAge(today time.Time) UserResponse {
+	response := u.ToResponse()
+	if age := CalculateAge(u.DOB, today); age > 0 {
+		response.Age = age
+	}
+	response.AgeAsOf = today.Format("2006-01-02")
+	// The body changes with the date, so a cached copy must not be
+	// revalidated on another
+	response.ETag = etag.WithVariant(response.ETag, today.Format("20060102"))
+	return response
+}
@@ -0,0 +1,402 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrTestFailed   = errors.New("patch test operation failed")
+)
+
+// Content types accepted for patch documents
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// Patch transforms a decoded JSON document
+type Patch interface {
+	Apply(doc interface{}) (interface{}, error)
+	// Members returns the top-level members of the document the patch
+	// writes, so callers can refuse changes to read-only ones
+	Members() []string
+}
+
+// MergePatch is a JSON Merge Patch document (RFC 7396)
+type MergePatch struct {
+	value interface{}
+}
+
+// ParseMergePatch decodes a JSON Merge Patch document
+func ParseMergePatch(data []byte) (*MergePatch, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return &MergePatch{value: value}, nil
+}
+
+// Apply merges the patch into doc. Members set to null are removed.
+func (p *MergePatch) Apply(doc interface{}) (interface{}, error) {
+	return mergePatch(doc, p.value), nil
+}
+
+// Members implements Patch. A patch that is not an object replaces the
+// whole document and names no members.
+func (p *MergePatch) Members() []string {
+	obj, ok := p.value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	members := make([]string, 0, len(obj))
+	for name := range obj {
+		members = append(members, name)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	} else {
+		targetObj = copyObject(targetObj)
+	}
+
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = mergePatch(targetObj[name], value)
+	}
+
+	return targetObj
+}
+
+// Operation is a single JSON Patch operation
+type Operation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from,omitempty"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is a JSON Patch document (RFC 6902)
+type JSONPatch []Operation
+
+// ParseJSONPatch decodes a JSON Patch document
+func ParseJSONPatch(data []byte) (JSONPatch, error) {
+	var ops JSONPatch
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return ops, nil
+}
+
+// Apply applies the operations in order. The patch is atomic: doc is never
+// modified and no result is returned if any operation fails.
+func (p JSONPatch) Apply(doc interface{}) (interface{}, error) {
+	doc = deepCopy(doc)
+
+	for i, op := range p {
+		var err error
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) is missing value", ErrInvalidPatch, i, op.Op)
+			}
+			var value interface{}
+			if err := json.Unmarshal(*op.Value, &value); err != nil {
+				return nil, fmt.Errorf("%w: operation %d has invalid value", ErrInvalidPatch, i)
+			}
+			switch op.Op {
+			case "add":
+				doc, err = add(doc, op.Path, value)
+			case "replace":
+				doc, err = replace(doc, op.Path, value)
+			case "test":
+				err = test(doc, op.Path, value)
+			}
+		case "remove":
+			doc, _, err = remove(doc, op.Path)
+		case "move":
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("%w: operation %d moves a value into its own child", ErrInvalidPatch, i)
+			}
+			var value interface{}
+			doc, value, err = remove(doc, op.From)
+			if err == nil {
+				doc, err = add(doc, op.Path, value)
+			}
+		case "copy":
+			var value interface{}
+			value, err = get(doc, op.From)
+			if err == nil {
+				doc, err = add(doc, op.Path, deepCopy(value))
+			}
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+
+		if err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: operation %d (%s %s): %v", ErrInvalidPatch, i, op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// Members implements Patch. Test operations only read, and a move also
+// writes the member it removes from.
+func (p JSONPatch) Members() []string {
+	seen := make(map[string]bool)
+	var members []string
+	addMember := func(pointer string) {
+		tokens, err := parsePointer(pointer)
+		if err != nil || len(tokens) == 0 || seen[tokens[0]] {
+			return
+		}
+		seen[tokens[0]] = true
+		members = append(members, tokens[0])
+	}
+	for _, op := range p {
+		switch op.Op {
+		case "test":
+			continue
+		case "move":
+			addMember(op.From)
+		}
+		addMember(op.Path)
+	}
+	return members
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token; "-" refers past the last element
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func get(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	}
+	return current, nil
+}
+
+// update replaces the value at pointer with the result of fn applied to
+// its parent container, returning the new document
+func update(doc interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path segment %q does not exist", tokens[0])
+		}
+		updated, err := update(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = updated
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(tokens[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(node[index], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	default:
+		return nil, fmt.Errorf("path segment %q does not exist", tokens[0])
+	}
+}
+
+func add(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return update(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("cannot add to non-container at %q", pointer)
+		}
+	})
+}
+
+func remove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the document root")
+	}
+
+	var removed interface{}
+	doc, err = update(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[index]
+			return append(node[:index], node[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	})
+	return doc, removed, err
+}
+
+func replace(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+	doc, _, err := remove(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return add(doc, pointer, value)
+}
+
+func test(doc interface{}, pointer string, value interface{}) error {
+	current, err := get(doc, pointer)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(current, value) {
+		return fmt.Errorf("%w: value at %q does not match", ErrTestFailed, pointer)
+	}
+	return nil
+}
+
+func copyObject(obj map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		copied[k] = v
+	}
+	return copied
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			copied[k] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
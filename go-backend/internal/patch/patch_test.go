@@ -0,0 +1,137 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", s, err)
+	}
+	return v
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"remove member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replace array", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`},
+		{"nested", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"non-object patch", `{"a":"b"}`, `["c"]`, `["c"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseMergePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("ParseMergePatch() error = %v", err)
+			}
+			got, err := p.Apply(decode(t, tt.doc))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if expected := decode(t, tt.expected); !reflect.DeepEqual(got, expected) {
+				t.Errorf("Apply() = %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"replace", `{"name":"a"}`, `[{"op":"replace","path":"/name","value":"b"}]`, `{"name":"b"}`},
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add to array end", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`},
+		{"insert into array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`},
+		{"remove", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`},
+		{"move", `{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`},
+		{"copy", `{"a":{"x":1}}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":{"x":1},"b":{"x":1}}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+		{"test then replace", `{"name":"a"}`, `[{"op":"test","path":"/name","value":"a"},{"op":"replace","path":"/name","value":"b"}]`, `{"name":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseJSONPatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("ParseJSONPatch() error = %v", err)
+			}
+			got, err := p.Apply(decode(t, tt.doc))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if expected := decode(t, tt.expected); !reflect.DeepEqual(got, expected) {
+				t.Errorf("Apply() = %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected error
+	}{
+		{"failed test", `[{"op":"test","path":"/name","value":"z"}]`, ErrTestFailed},
+		{"missing path", `[{"op":"replace","path":"/missing","value":1}]`, ErrInvalidPatch},
+		{"unknown op", `[{"op":"frobnicate","path":"/name"}]`, ErrInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/x"}]`, ErrInvalidPatch},
+		{"bad pointer", `[{"op":"remove","path":"name"}]`, ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := decode(t, `{"name":"a"}`)
+			p, err := ParseJSONPatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("ParseJSONPatch() error = %v", err)
+			}
+			if _, err := p.Apply(doc); !errors.Is(err, tt.expected) {
+				t.Errorf("Apply() error = %v, expected %v", err, tt.expected)
+			}
+			if !reflect.DeepEqual(doc, decode(t, `{"name":"a"}`)) {
+				t.Errorf("Apply() modified the original document: %v", doc)
+			}
+		})
+	}
+}
+
+func TestMembers(t *testing.T) {
+	merge, err := ParseMergePatch([]byte(`{"name":"a","id":null}`))
+	if err != nil {
+		t.Fatalf("ParseMergePatch() error = %v", err)
+	}
+	if got := merge.Members(); !reflect.DeepEqual(got, []string{"id", "name"}) {
+		t.Errorf("MergePatch.Members() = %v, expected [id name]", got)
+	}
+
+	ops, err := ParseJSONPatch([]byte(`[
+		{"op":"test","path":"/id","value":1},
+		{"op":"replace","path":"/name","value":"b"},
+		{"op":"move","from":"/age","path":"/dob"},
+		{"op":"add","path":"/name~1x/y","value":1}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseJSONPatch() error = %v", err)
+	}
+	if got := ops.Members(); !reflect.DeepEqual(got, []string{"name", "age", "dob", "name/x"}) {
+		t.Errorf("JSONPatch.Members() = %v, expected [name age dob name/x]", got)
+	}
+}
@@ -0,0 +1,166 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// bucket storage.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit allows bursts of up to Requests and refills the bucket at
+// Requests per Period. The zero Limit disables limiting.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Unlimited reports whether the limit disables limiting
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// rate returns the refill rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// String formats the limit the way ParseLimit reads it
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit reads a limit written as "<requests>/<period>", for example
+// "60/1m", or "off" for no limit
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w %q, use <requests>/<period>", ErrInvalidLimit, s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%w %q: requests must be a positive integer", ErrInvalidLimit, s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%w %q: period must be a positive duration", ErrInvalidLimit, s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Result describes the state of a bucket after a request took from it
+type Result struct {
+	// Allowed reports whether the request may proceed
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until a denied request could succeed
+	RetryAfter time.Duration
+}
+
+// Store holds token buckets. Implementations backed by a shared service
+// let several replicas enforce one limit.
+type Store interface {
+	// Take removes a token from the bucket for key, creating a full bucket
+	// with the given limit when none exists
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// sweepInterval is how often the memory store drops buckets that have
+// refilled completely and so carry no state
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are enforced per
+// replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory Store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
+	}
+
+	rate := limit.rate()
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+elapsed*rate)
+		b.updated = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Requests) - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep drops full buckets at most once per sweepInterval
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !b.full.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
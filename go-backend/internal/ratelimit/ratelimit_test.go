@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"60/1m", Limit{Requests: 60, Period: time.Minute}, false},
+		{" 10 / 1s ", Limit{Requests: 10, Period: time.Second}, false},
+		{"off", Limit{}, false},
+		{"60", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"ten/1m", Limit{}, true},
+		{"10/forever", Limit{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLimit) {
+					t.Errorf("ParseLimit() error = %v, expected ErrInvalidLimit", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseLimit() = %v, %v, expected %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take(ctx, "client", limit)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if !res.Allowed || res.Remaining != i {
+			t.Fatalf("Take() = %+v, expected allowed with %d remaining", res, i)
+		}
+	}
+
+	res, _ := store.Take(ctx, "client", limit)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Errorf("Take() on empty bucket = %+v, expected denied with 1s retry and 3s reset", res)
+	}
+
+	// Other keys have their own bucket
+	if res, _ := store.Take(ctx, "other", limit); !res.Allowed {
+		t.Errorf("Take() for another key = %+v, expected allowed", res)
+	}
+
+	// One token refills per second
+	now = now.Add(time.Second)
+	if res, _ := store.Take(ctx, "client", limit); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Take() after refill = %+v, expected allowed with 0 remaining", res)
+	}
+
+	// Buckets never hold more than the burst
+	now = now.Add(time.Hour)
+	if res, _ := store.Take(ctx, "client", limit); res.Remaining != 2 {
+		t.Errorf("Take() after a long pause = %+v, expected 2 remaining", res)
+	}
+	if len(store.buckets) != 1 {
+		t.Errorf("Expected full buckets to be swept, %d remain", len(store.buckets))
+	}
+}
+
+func TestMemoryStore_Unlimited(t *testing.T) {
+	store := NewMemoryStore()
+	for i := 0; i < 100; i++ {
+		if res, _ := store.Take(context.Background(), "client", Limit{}); !res.Allowed {
+			t.Fatalf("Take() with no limit = %+v, expected allowed", res)
+		}
+	}
+}
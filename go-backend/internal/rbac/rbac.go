@@ -0,0 +1,94 @@
+// Package rbac maps caller roles to the permissions they grant.
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Permissions on user resources
+const (
+	PermUsersRead   = "users:read"
+	PermUsersWrite  = "users:write"
+	PermUsersDelete = "users:delete"
+	// PermAPIKeysManage allows minting, listing, rotating and revoking API keys
+	PermAPIKeysManage = "api_keys:manage"
+)
+
+var ErrInvalidPolicy = errors.New("invalid RBAC policy")
+
+// Policy grants permissions to roles
+type Policy struct {
+	roles map[string]map[string]bool
+}
+
+// policyFile is the JSON layout of a policy file:
+//
+//	{"roles": {"viewer": ["users:read"], "admin": ["users:read", "users:write"]}}
+type policyFile struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+// NewPolicy creates a Policy from a role to permissions mapping
+func NewPolicy(roles map[string][]string) *Policy {
+	p := &Policy{roles: make(map[string]map[string]bool, len(roles))}
+	for role, permissions := range roles {
+		granted := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			granted[permission] = true
+		}
+		p.roles[role] = granted
+	}
+	return p
+}
+
+// DefaultPolicy grants viewers read access, editors read and write access
+// and admins every permission
+func DefaultPolicy() *Policy {
+	return NewPolicy(map[string][]string{
+		"viewer": {PermUsersRead},
+		"editor": {PermUsersRead, PermUsersWrite},
+		"admin":  {PermUsersRead, PermUsersWrite, PermUsersDelete, PermAPIKeysManage},
+	})
+}
+
+// LoadPolicy reads a JSON policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RBAC policy: %w", err)
+	}
+
+	var file policyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if len(file.Roles) == 0 {
+		return nil, fmt.Errorf("%w: no roles defined", ErrInvalidPolicy)
+	}
+
+	return NewPolicy(file.Roles), nil
+}
+
+// Allows reports whether any of the roles grants the permission
+func (p *Policy) Allows(roles []string, permission string) bool {
+	for _, role := range roles {
+		if p.roles[role][permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles lists the roles known to the policy
+func (p *Policy) Roles() []string {
+	roles := make([]string, 0, len(p.roles))
+	for role := range p.roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
@@ -0,0 +1,60 @@
+package rbac
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := DefaultPolicy()
+
+	tests := []struct {
+		roles      []string
+		permission string
+		want       bool
+	}{
+		{[]string{"viewer"}, PermUsersRead, true},
+		{[]string{"viewer"}, PermUsersWrite, false},
+		{[]string{"editor"}, PermUsersWrite, true},
+		{[]string{"editor"}, PermUsersDelete, false},
+		{[]string{"admin"}, PermUsersDelete, true},
+		{[]string{"viewer", "editor"}, PermUsersWrite, true},
+		{[]string{"unknown"}, PermUsersRead, false},
+		{nil, PermUsersRead, false},
+	}
+
+	for _, tt := range tests {
+		if got := p.Allows(tt.roles, tt.permission); got != tt.want {
+			t.Errorf("Allows(%v, %q) = %v, expected %v", tt.roles, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(path, []byte(`{"roles": {"auditor": ["users:read"], "ops": ["users:delete"]}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	p, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+	if !p.Allows([]string{"ops"}, PermUsersDelete) || p.Allows([]string{"ops"}, PermUsersRead) {
+		t.Error("LoadPolicy() did not apply the file's grants")
+	}
+	if roles := p.Roles(); len(roles) != 2 || roles[0] != "auditor" || roles[1] != "ops" {
+		t.Errorf("Roles() = %v, expected [auditor ops]", roles)
+	}
+
+	empty := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(empty, []byte(`{"roles": {}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := LoadPolicy(empty); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("LoadPolicy() error = %v, expected ErrInvalidPolicy", err)
+	}
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"user-api/db/sqlc"
+	"user-api/internal/models"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyRevoked  = errors.New("api key is revoked")
+)
+
+// APIKeyRepository defines the interface for API key data access
+type APIKeyRepository interface {
+	Create(ctx context.Context, name, prefix string, salt, hash []byte, scopes []string) (*models.APIKey, error)
+	GetByID(ctx context.Context, id int64) (*models.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	List(ctx context.Context) ([]*models.APIKey, error)
+	// Rotate revokes an active key and creates its replacement with the same
+	// name and scopes in one transaction
+	Rotate(ctx context.Context, id int64, prefix string, salt, hash []byte) (*models.APIKey, error)
+	Revoke(ctx context.Context, id int64) (*models.APIKey, error)
+	// TouchLastUsed records a use, at most once a minute per key
+	TouchLastUsed(ctx context.Context, id int64) error
+}
+
+type apiKeyRepository struct {
+	db      *sql.DB
+	queries *sqlc.Queries
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository instance
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db, queries: sqlc.New(db)}
+}
+
+// Create stores a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, name, prefix string, salt, hash []byte, scopes []string) (*models.APIKey, error) {
+	row, err := r.queries.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		Name:   name,
+		Prefix: prefix,
+		Salt:   salt,
+		Hash:   hash,
+		Scopes: scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toAPIKey(row), nil
+}
+
+// GetByID retrieves an API key by ID
+func (r *apiKeyRepository) GetByID(ctx context.Context, id int64) (*models.APIKey, error) {
+	row, err := r.queries.GetAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return toAPIKey(row), nil
+}
+
+// GetByPrefix retrieves an API key by its lookup prefix
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	row, err := r.queries.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return toAPIKey(row), nil
+}
+
+// List retrieves all API keys, including revoked ones
+func (r *apiKeyRepository) List(ctx context.Context) ([]*models.APIKey, error) {
+	rows, err := r.queries.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*models.APIKey, len(rows))
+	for i, row := range rows {
+		keys[i] = toAPIKey(row)
+	}
+	return keys, nil
+}
+
+// Rotate revokes a key and creates its replacement atomically
+func (r *apiKeyRepository) Rotate(ctx context.Context, id int64, prefix string, salt, hash []byte) (*models.APIKey, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := r.queries.WithTx(tx)
+	old, err := q.RevokeAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.revokeError(ctx, id)
+		}
+		return nil, err
+	}
+
+	row, err := q.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		Name:   old.Name,
+		Prefix: prefix,
+		Salt:   salt,
+		Hash:   hash,
+		Scopes: old.Scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return toAPIKey(row), nil
+}
+
+// Revoke marks a key as revoked
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64) (*models.APIKey, error) {
+	row, err := r.queries.RevokeAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.revokeError(ctx, id)
+		}
+		return nil, err
+	}
+
+	return toAPIKey(row), nil
+}
+
+// revokeError explains why revoking matched no rows: either the key does
+// not exist or it was already revoked
+func (r *apiKeyRepository) revokeError(ctx context.Context, id int64) error {
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return ErrAPIKeyRevoked
+}
+
+// TouchLastUsed stamps last_used_at
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	return r.queries.TouchAPIKey(ctx, id)
+}
+
+// toAPIKey converts a generated row into the domain model
+func toAPIKey(k sqlc.APIKey) *models.APIKey {
+	key := &models.APIKey{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Salt:      k.Salt,
+		Hash:      k.Hash,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+	}
+	if k.LastUsedAt.Valid {
+		lastUsedAt := k.LastUsedAt.Time
+		key.LastUsedAt = &lastUsedAt
+	}
+	if k.RevokedAt.Valid {
+		revokedAt := k.RevokedAt.Time
+		key.RevokedAt = &revokedAt
+	}
+	return key
+}
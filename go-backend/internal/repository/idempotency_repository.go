@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"user-api/db/sqlc"
+	"user-api/internal/models"
+)
+
+// IdempotencyRepository defines the interface for idempotency key storage
+type IdempotencyRepository interface {
+	// Claim records a new in-progress request for the key and reports true,
+	// or returns the live record already holding the key and false. Expired
+	// records, and in-progress records whose lease has run out because the
+	// request never completed, are replaced as if they did not exist.
+	Claim(ctx context.Context, scope, key string, fingerprint []byte, expiresAt time.Time) (*models.IdempotencyRecord, bool, error)
+	// Complete stores the response of a claimed request
+	Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error
+	// Release forgets a claimed key so the request can be retried
+	Release(ctx context.Context, scope, key string) error
+	// PurgeExpired deletes expired records and returns how many were removed
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// maxClaimAttempts bounds how often Claim retries when the record it lost
+// to is released before it can be read
+const maxClaimAttempts = 3
+
+type idempotencyRepository struct {
+	queries *sqlc.Queries
+	lease   time.Duration
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository instance.
+// An in-progress claim older than lease is taken to belong to a request
+// that crashed, and can be claimed again by a retry.
+func NewIdempotencyRepository(db *sql.DB, lease time.Duration) IdempotencyRepository {
+	return &idempotencyRepository{queries: sqlc.New(db), lease: lease}
+}
+
+// Claim inserts an in-progress record unless a live one exists
+func (r *idempotencyRepository) Claim(ctx context.Context, scope, key string, fingerprint []byte, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	for attempt := 1; ; attempt++ {
+		row, err := r.queries.ClaimIdempotencyKey(ctx, sqlc.ClaimIdempotencyKeyParams{
+			Scope:        scope,
+			Key:          key,
+			Fingerprint:  fingerprint,
+			ExpiresAt:    expiresAt,
+			LeaseSeconds: r.lease.Seconds(),
+		})
+		if err == nil {
+			record, err := toIdempotencyRecord(row)
+			return record, true, err
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, err
+		}
+
+		// The insert hit a live record
+		row, err = r.queries.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{Scope: scope, Key: key})
+		if errors.Is(err, sql.ErrNoRows) && attempt < maxClaimAttempts {
+			// Its holder released it in the meantime, so the key is free
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		record, err := toIdempotencyRecord(row)
+		return record, false, err
+	}
+}
+
+// Complete stores the response of a claimed request
+func (r *idempotencyRepository) Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error {
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	return r.queries.CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		StatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: true},
+		Headers:    encoded,
+		Body:       body,
+		Scope:      scope,
+		Key:        key,
+	})
+}
+
+// Release deletes a claimed key
+func (r *idempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	return r.queries.DeleteIdempotencyKey(ctx, sqlc.DeleteIdempotencyKeyParams{Scope: scope, Key: key})
+}
+
+// PurgeExpired deletes expired records
+func (r *idempotencyRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	return r.queries.PurgeExpiredIdempotencyKeys(ctx)
+}
+
+// toIdempotencyRecord converts a generated row into the domain model
+func toIdempotencyRecord(k sqlc.IdempotencyKey) (*models.IdempotencyRecord, error) {
+	record := &models.IdempotencyRecord{
+		Scope:       k.Scope,
+		Key:         k.Key,
+		Fingerprint: k.Fingerprint,
+		Body:        k.Body,
+		CreatedAt:   k.CreatedAt,
+		ExpiresAt:   k.ExpiresAt,
+	}
+	if k.StatusCode.Valid {
+		record.StatusCode = int(k.StatusCode.Int32)
+	}
+	if len(k.Headers) > 0 {
+		if err := json.Unmarshal(k.Headers, &record.Headers); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
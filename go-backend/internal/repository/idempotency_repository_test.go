@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+// TestIdempotencyClaim_Postgres checks that an in-progress claim keeps its
+// key until its lease runs out, and a completed one until it expires
+func TestIdempotencyClaim_Postgres(t *testing.T) {
+	db := openTestDB(t)
+	lease := time.Second
+	repo := NewIdempotencyRepository(db, lease)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	claim := func(key string, fingerprint []byte, wantClaimed bool) {
+		t.Helper()
+		record, claimed, err := repo.Claim(ctx, "scope", key, fingerprint, expiresAt)
+		if err != nil {
+			t.Fatalf("Claim(%s) error = %v", key, err)
+		}
+		if claimed != wantClaimed {
+			t.Fatalf("Claim(%s) claimed = %v, expected %v", key, claimed, wantClaimed)
+		}
+		if wantClaimed && (!bytes.Equal(record.Fingerprint, fingerprint) || record.Completed()) {
+			t.Errorf("Claim(%s) record = %+v, expected a new in-progress record", key, record)
+		}
+	}
+
+	claim("crashed", []byte("first"), true)
+	claim("crashed", []byte("retry"), false)
+	claim("done", []byte("first"), true)
+	if err := repo.Complete(ctx, "scope", "done", 201, map[string]string{"Location": "/users/1"}, []byte("{}")); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	time.Sleep(lease + 100*time.Millisecond)
+
+	// The crashed request's lease has run out, so a retry takes its key
+	claim("crashed", []byte("retry"), true)
+	claim("crashed", []byte("retry"), false)
+
+	// Completed responses are kept until they expire
+	record, claimed, err := repo.Claim(ctx, "scope", "done", []byte("first"), expiresAt)
+	if err != nil {
+		t.Fatalf("Claim(done) error = %v", err)
+	}
+	if claimed || record.StatusCode != 201 || record.Headers["Location"] != "/users/1" {
+		t.Errorf("Claim(done) = %+v, %v, expected the completed record", record, claimed)
+	}
+
+	// A released key can be claimed again at once
+	if err := repo.Release(ctx, "scope", "done"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	claim("done", []byte("second"), true)
+}
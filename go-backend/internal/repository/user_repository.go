@@ -4,73 +4,223 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"user-api/db/sqlc"
 	"user-api/internal/models"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrInvalidKeyset  = errors.New("keyset does not match sort keys")
+	ErrStaleVersion   = errors.New("user was modified concurrently")
+	ErrUserNotDeleted = errors.New("user is not deleted")
 )
 
-// UserRepository defines the interface for user data access
+// UserRepository defines the interface for user data access.
+// Deleted users are soft deleted and hidden from reads unless requested.
+// Write methods accept an optional version: when non-nil the write only
+// applies if the row's updated_at still equals it, and ErrStaleVersion is
+// returned otherwise.
 type UserRepository interface {
 	Create(ctx context.Context, name string, dob time.Time) (*models.User, error)
-	GetByID(ctx context.Context, id int64) (*models.User, error)
-	Update(ctx context.Context, id int64, name string, dob time.Time) (*models.User, error)
-	Delete(ctx context.Context, id int64) error
-	List(ctx context.Context, limit, offset int) ([]*models.User, error)
-	Count(ctx context.Context) (int64, error)
+	BulkCreate(ctx context.Context, users []models.NewUser, bestEffort bool) ([]models.BulkCreateResult, error)
+	GetByID(ctx context.Context, id int64, includeDeleted bool) (*models.User, error)
+	Update(ctx context.Context, id int64, name string, dob time.Time, version *time.Time) (*models.User, error)
+	Patch(ctx context.Context, id int64, patch models.UserPatch, version *time.Time) (*models.User, error)
+	Delete(ctx context.Context, id int64, version *time.Time) error
+	Restore(ctx context.Context, id int64, version *time.Time) (*models.User, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	List(ctx context.Context, opts models.UserListOptions) ([]*models.User, error)
+	Stream(ctx context.Context, opts models.UserListOptions, fn func(*models.User) error) error
+	Count(ctx context.Context, filter models.UserFilter) (int64, error)
+	// ListBirthdays returns active users whose next birthday on or after
+	// opts.From is no later than opts.To, ordered by that birthday
+	ListBirthdays(ctx context.Context, opts models.BirthdayListOptions) ([]models.UpcomingBirthday, error)
+	CountBirthdays(ctx context.Context, from, to time.Time) (int64, error)
 }
 
+// userRepository runs the static queries through the sqlc-generated
+// Querier. Listing, streaming and counting build their SQL dynamically from
+// filters and sort keys, which sqlc cannot express, and use db directly.
 type userRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	queries *sqlc.Queries
 }
 
 // NewUserRepository creates a new UserRepository instance
 func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db: db}
+	return &userRepository{db: db, queries: sqlc.New(db)}
 }
 
 // Create inserts a new user into the database
 func (r *userRepository) Create(ctx context.Context, name string, dob time.Time) (*models.User, error) {
-	query := `
-		INSERT INTO users (name, dob)
-		VALUES ($1, $2)
-		RETURNING id, name, dob, created_at, updated_at
-	`
-
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, name, dob).Scan(
-		&user.ID,
-		&user.Name,
-		&user.DOB,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	row, err := r.queries.CreateUser(ctx, sqlc.CreateUserParams{Name: name, Dob: dob})
 	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	return toUser(row), nil
 }
 
-// GetByID retrieves a user by ID
-func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
-	query := `
-		SELECT id, name, dob, created_at, updated_at
-		FROM users
-		WHERE id = $1
-	`
-
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.DOB,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+// bulkBatchSize is the number of users inserted per statement by BulkCreate
+const bulkBatchSize = 500
+
+// BulkCreate inserts users in batches inside a single transaction and
+// returns their ids in input order. In best-effort mode each batch runs
+// under a savepoint; when one fails it is rolled back and retried row by
+// row, so only the offending users are rejected, each with its own error,
+// while every other user is still committed. Otherwise any error rolls
+// back the whole import.
+func (r *userRepository) BulkCreate(ctx context.Context, users []models.NewUser, bestEffort bool) ([]models.BulkCreateResult, error) {
+	results := make([]models.BulkCreateResult, len(users))
+	err := r.withTx(ctx, func(tx *sql.Tx, q sqlc.Querier) error {
+		for start := 0; start < len(users); start += bulkBatchSize {
+			end := min(start+bulkBatchSize, len(users))
+
+			if !bestEffort {
+				ids, err := insertBatch(ctx, q, users[start:end])
+				if err != nil {
+					return err
+				}
+				for i, id := range ids {
+					results[start+i].ID = id
+				}
+				continue
+			}
+
+			var ids []int64
+			err := withSavepoint(ctx, tx, "bulk_batch", func() error {
+				var err error
+				ids, err = insertBatch(ctx, q, users[start:end])
+				return err
+			})
+			switch {
+			case errors.As(err, new(*savepointError)):
+				return err
+			case err != nil:
+				// Find the offending users and keep the rest of the batch
+				if err := insertRows(ctx, tx, q, users[start:end], results[start:end]); err != nil {
+					return err
+				}
+			default:
+				for i, id := range ids {
+					results[start+i].ID = id
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// insertRows inserts users one at a time, each under its own savepoint, and
+// records each user's id or error in results. It fails only when a
+// savepoint itself cannot be managed.
+func insertRows(ctx context.Context, tx *sql.Tx, q sqlc.Querier, users []models.NewUser, results []models.BulkCreateResult) error {
+	for i, u := range users {
+		err := withSavepoint(ctx, tx, "bulk_row", func() error {
+			row, err := q.CreateUser(ctx, sqlc.CreateUserParams{Name: u.Name, Dob: u.DOB})
+			if err != nil {
+				return err
+			}
+			results[i].ID = row.ID
+			return nil
+		})
+		if errors.As(err, new(*savepointError)) {
+			return err
+		}
+		results[i].Err = err
+	}
+	return nil
+}
+
+// savepointError is returned by withSavepoint when the savepoint could not
+// be created, released or rolled back to, leaving the transaction unusable
+type savepointError struct {
+	err error
+}
+
+func (e *savepointError) Error() string { return "savepoint: " + e.err.Error() }
+func (e *savepointError) Unwrap() error { return e.err }
+
+// withSavepoint runs fn under the named savepoint, releasing it when fn
+// succeeds and rolling back to it otherwise. fn's error is returned as is.
+func withSavepoint(ctx context.Context, tx *sql.Tx, name string, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return &savepointError{err}
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return &savepointError{rbErr}
+		}
+		if _, relErr := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); relErr != nil {
+			return &savepointError{relErr}
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return &savepointError{err}
+	}
+	return nil
+}
+
+// insertBatch inserts users with a single statement and returns their ids
+// in input order. Each id comes back with the 1-based ordinal of its row,
+// as neither the insert order nor the sequence values follow the input.
+func insertBatch(ctx context.Context, q sqlc.Querier, users []models.NewUser) ([]int64, error) {
+	params := sqlc.CreateUsersParams{
+		Names: make([]string, len(users)),
+		Dobs:  make([]time.Time, len(users)),
+	}
+	for i, u := range users {
+		params.Names[i] = u.Name
+		params.Dobs[i] = u.DOB
+	}
+
+	rows, err := q.CreateUsers(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != len(users) {
+		return nil, fmt.Errorf("inserted %d of %d users", len(rows), len(users))
+	}
+
+	ids := make([]int64, len(users))
+	for _, row := range rows {
+		if row.Ord < 1 || row.Ord > int64(len(users)) {
+			return nil, fmt.Errorf("inserted user has ordinal %d of %d", row.Ord, len(users))
+		}
+		ids[row.Ord-1] = row.ID
+	}
+	return ids, nil
+}
+
+// withTx runs fn in a transaction with a Querier bound to it, committing
+// when fn succeeds and rolling back otherwise
+func (r *userRepository) withTx(ctx context.Context, fn func(tx *sql.Tx, q sqlc.Querier) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx, r.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves a user by ID, optionally including soft-deleted users
+func (r *userRepository) GetByID(ctx context.Context, id int64, includeDeleted bool) (*models.User, error) {
+	row, err := r.queries.GetUser(ctx, sqlc.GetUserParams{ID: id, IncludeDeleted: includeDeleted})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -78,104 +228,445 @@ func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, e
 		return nil, err
 	}
 
-	return user, nil
+	return toUser(row), nil
 }
 
 // Update modifies an existing user
-func (r *userRepository) Update(ctx context.Context, id int64, name string, dob time.Time) (*models.User, error) {
-	query := `
-		UPDATE users
-		SET name = $2, dob = $3
-		WHERE id = $1
-		RETURNING id, name, dob, created_at, updated_at
-	`
-
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id, name, dob).Scan(
-		&user.ID,
-		&user.Name,
-		&user.DOB,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+func (r *userRepository) Update(ctx context.Context, id int64, name string, dob time.Time, version *time.Time) (*models.User, error) {
+	row, err := r.queries.UpdateUser(ctx, sqlc.UpdateUserParams{
+		Name:    name,
+		Dob:     dob,
+		ID:      id,
+		Version: nullTime(version),
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrUserNotFound
+			return nil, r.missingRowError(ctx, id, version)
 		}
 		return nil, err
 	}
 
-	return user, nil
+	return toUser(row), nil
 }
 
-// Delete removes a user from the database
-func (r *userRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM users WHERE id = $1`
+// Patch modifies only the supplied columns of an existing user
+func (r *userRepository) Patch(ctx context.Context, id int64, patch models.UserPatch, version *time.Time) (*models.User, error) {
+	// Nothing to change, return the current state untouched rather than
+	// letting the update trigger bump updated_at
+	if patch.Name == nil && patch.DOB == nil {
+		user, err := r.GetByID(ctx, id, false)
+		if err != nil {
+			return nil, err
+		}
+		if version != nil && !user.UpdatedAt.Equal(*version) {
+			return nil, ErrStaleVersion
+		}
+		return user, nil
+	}
+
+	params := sqlc.PatchUserParams{
+		Dob:     nullTime(patch.DOB),
+		ID:      id,
+		Version: nullTime(version),
+	}
+	if patch.Name != nil {
+		params.Name = sql.NullString{String: *patch.Name, Valid: true}
+	}
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	row, err := r.queries.PatchUser(ctx, params)
 	if err != nil {
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.missingRowError(ctx, id, version)
+		}
+		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return toUser(row), nil
+}
+
+// Delete soft deletes a user by stamping deleted_at
+func (r *userRepository) Delete(ctx context.Context, id int64, version *time.Time) error {
+	rowsAffected, err := r.queries.DeleteUser(ctx, sqlc.DeleteUserParams{ID: id, Version: nullTime(version)})
 	if err != nil {
 		return err
 	}
 
 	if rowsAffected == 0 {
-		return ErrUserNotFound
+		return r.missingRowError(ctx, id, version)
 	}
 
 	return nil
 }
 
-// List retrieves users with pagination
-func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models.User, error) {
-	query := `
-		SELECT id, name, dob, created_at, updated_at
-		FROM users
-		ORDER BY id
-		LIMIT $1 OFFSET $2
-	`
+// missingRowError explains why a versioned write matched no rows: either the
+// user does not exist or its version has moved on
+func (r *userRepository) missingRowError(ctx context.Context, id int64, version *time.Time) error {
+	if version == nil {
+		return ErrUserNotFound
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	exists, err := r.queries.UserExists(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	if !exists {
+		return ErrUserNotFound
+	}
+	return ErrStaleVersion
+}
 
-	var users []*models.User
-	for rows.Next() {
-		user := &models.User{}
-		err := rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.DOB,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+// Restore clears deleted_at on a soft-deleted user
+func (r *userRepository) Restore(ctx context.Context, id int64, version *time.Time) (*models.User, error) {
+	row, err := r.queries.RestoreUser(ctx, sqlc.RestoreUserParams{ID: id, Version: nullTime(version)})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		user, err := r.GetByID(ctx, id, true)
 		if err != nil {
 			return nil, err
 		}
-		users = append(users, user)
+		if user.DeletedAt == nil {
+			return nil, ErrUserNotDeleted
+		}
+		return nil, ErrStaleVersion
 	}
 
-	if err := rows.Err(); err != nil {
+	return toUser(row), nil
+}
+
+// PurgeDeleted permanently removes users soft deleted before the given time
+func (r *userRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return r.queries.PurgeDeletedUsers(ctx, sql.NullTime{Time: deletedBefore, Valid: true})
+}
+
+// List retrieves users matching the filter with pagination
+func (r *userRepository) List(ctx context.Context, opts models.UserListOptions) ([]*models.User, error) {
+	var users []*models.User
+	err := r.Stream(ctx, opts, func(user *models.User) error {
+		users = append(users, user)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return users, nil
 }
 
-// Count returns the total number of users
-func (r *userRepository) Count(ctx context.Context) (int64, error) {
-	query := `SELECT COUNT(*) FROM users`
+// Stream calls fn for every user matching the options as rows arrive from
+// the database, without loading the result set into memory. A zero Limit
+// streams all matching users. Iteration stops at the first error from fn.
+func (r *userRepository) Stream(ctx context.Context, opts models.UserListOptions, fn func(*models.User) error) error {
+	query, args, err := listQuery(opts)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// listQuery builds the SELECT statement for List and Stream
+func listQuery(opts models.UserListOptions) (string, []interface{}, error) {
+	qb := &queryBuilder{}
+	keys := sortKeys(opts.Sort)
+
+	conditions := qb.conditions(opts.Filter)
+	if opts.After != nil {
+		seek, err := qb.seek(keys, opts.After)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, seek)
+		if opts.After.Backward {
+			keys = reverseKeys(keys)
+		}
+	}
+
+	var limit string
+	if opts.Limit > 0 {
+		limit = fmt.Sprintf("LIMIT %s OFFSET %s", qb.arg(opts.Limit), qb.arg(opts.Offset))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name, dob, created_at, updated_at, deleted_at
+		FROM users
+		%s
+		ORDER BY %s
+		%s
+	`, where(conditions), orderBy(keys), limit)
+
+	return query, qb.args, nil
+}
+
+// Count returns the number of users matching the filter
+func (r *userRepository) Count(ctx context.Context, filter models.UserFilter) (int64, error) {
+	qb := &queryBuilder{}
+	query := `SELECT COUNT(*) FROM users ` + where(qb.conditions(filter))
 
 	var count int64
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query, qb.args...).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 
 	return count, nil
 }
+
+// ListBirthdays returns users with an upcoming birthday in the given range.
+// The query finds each active user's next birthday on or after opts.From as
+// models.NextBirthday computes it: adding whole years to a date moves
+// February 29 to February 28 in common years, and birthdays before
+// opts.From move to the next year, so ranges may wrap past December.
+func (r *userRepository) ListBirthdays(ctx context.Context, opts models.BirthdayListOptions) ([]models.UpcomingBirthday, error) {
+	rows, err := r.queries.ListBirthdays(ctx, sqlc.ListBirthdaysParams{
+		Year:      int32(opts.From.Year()),
+		FromDate:  opts.From,
+		ToDate:    opts.To,
+		RowOffset: int32(opts.Offset),
+		RowLimit:  int32(opts.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	birthdays := make([]models.UpcomingBirthday, len(rows))
+	for i, row := range rows {
+		birthdays[i] = models.UpcomingBirthday{
+			User: toUser(sqlc.User{
+				ID:        row.ID,
+				Name:      row.Name,
+				Dob:       row.Dob,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+				DeletedAt: row.DeletedAt,
+			}),
+			Birthday: row.Birthday,
+		}
+	}
+
+	return birthdays, nil
+}
+
+// CountBirthdays returns the number of users with an upcoming birthday in
+// the given range
+func (r *userRepository) CountBirthdays(ctx context.Context, from, to time.Time) (int64, error) {
+	return r.queries.CountBirthdays(ctx, sqlc.CountBirthdaysParams{
+		Year:     int32(from.Year()),
+		FromDate: from,
+		ToDate:   to,
+	})
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a row of the columns id, name, dob, created_at,
+// updated_at and deleted_at
+func scanUser(row rowScanner) (*models.User, error) {
+	var u sqlc.User
+	err := row.Scan(
+		&u.ID,
+		&u.Name,
+		&u.Dob,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		&u.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(u), nil
+}
+
+// toUser converts a generated row into the domain model
+func toUser(u sqlc.User) *models.User {
+	user := &models.User{
+		ID:        u.ID,
+		Name:      u.Name,
+		DOB:       u.Dob,
+		CreatedAt: u.CreatedAt.Time,
+		UpdatedAt: u.UpdatedAt.Time,
+	}
+	if u.DeletedAt.Valid {
+		deletedAt := u.DeletedAt.Time
+		user.DeletedAt = &deletedAt
+	}
+	return user
+}
+
+// nullTime converts an optional time into a nullable query parameter
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// queryBuilder accumulates positional arguments for dynamically built queries
+type queryBuilder struct {
+	args []interface{}
+}
+
+// arg registers a query argument and returns its placeholder
+func (b *queryBuilder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// conditions translates a UserFilter into WHERE conditions. Age bounds are
+// converted into DOB bounds relative to f.AgeOn so every predicate can use a
+// plain comparison on the dob column.
+func (b *queryBuilder) conditions(f models.UserFilter) []string {
+	var conditions []string
+
+	if !f.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	// Served by the trigram index idx_users_name_trgm
+	if f.NameContains != "" {
+		conditions = append(conditions, "name ILIKE "+b.arg("%"+escapeLike(f.NameContains)+"%"))
+	}
+	// A left-anchored LIKE is served by idx_users_name_pattern
+	if f.NamePrefix != "" {
+		conditions = append(conditions, "name LIKE "+b.arg(escapeLike(f.NamePrefix)+"%"))
+	}
+	if f.DOBFrom != nil {
+		conditions = append(conditions, "dob >= "+b.arg(*f.DOBFrom))
+	}
+	if f.DOBTo != nil {
+		conditions = append(conditions, "dob <= "+b.arg(*f.DOBTo))
+	}
+
+	// Age >= MinAge means born on or before the latest DOB of that age
+	if f.MinAge != nil {
+		conditions = append(conditions, "dob <= "+b.arg(models.LatestDOBForAge(f.AgeOn, *f.MinAge)))
+	}
+	// Age <= MaxAge means born after the latest DOB of age MaxAge+1
+	if f.MaxAge != nil {
+		conditions = append(conditions, "dob > "+b.arg(models.LatestDOBForAge(f.AgeOn, *f.MaxAge+1)))
+	}
+
+	return conditions
+}
+
+// seek builds the keyset predicate selecting rows strictly after the
+// boundary row in the given key order (or before it when paging backward).
+// For keys (k1, k2, id) this expands to
+// k1 > v1 OR (k1 = v1 AND k2 > v2) OR (k1 = v1 AND k2 = v2 AND id > v3).
+func (b *queryBuilder) seek(keys []sortKey, after *models.Keyset) (string, error) {
+	if len(after.Values) != len(keys)-1 {
+		return "", ErrInvalidKeyset
+	}
+
+	placeholders := make([]string, len(keys))
+	for i := range keys {
+		if i == len(keys)-1 {
+			placeholders[i] = b.arg(after.ID)
+		} else {
+			placeholders[i] = b.arg(after.Values[i])
+		}
+	}
+
+	var branches []string
+	for i, key := range keys {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, keys[j].column+" = "+placeholders[j])
+		}
+		op := ">"
+		if key.desc != after.Backward {
+			op = "<"
+		}
+		terms = append(terms, key.column+" "+op+" "+placeholders[i])
+		branches = append(branches, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(branches, " OR ") + ")", nil
+}
+
+// where joins conditions into a WHERE clause
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// sortColumns maps sortable fields to their database columns
+var sortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"dob":        "dob",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// sortKey is a resolved ORDER BY term
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// sortKeys resolves sort fields into ORDER BY terms. The id column is
+// always the last key, appended as a tie-breaker when not requested, so
+// the order is total and paging is stable.
+func sortKeys(sort []models.SortField) []sortKey {
+	var keys []sortKey
+	for _, f := range sort {
+		column, ok := sortColumns[f.Field]
+		if !ok {
+			continue
+		}
+		keys = append(keys, sortKey{column: column, desc: f.Desc})
+		if column == "id" {
+			return keys
+		}
+	}
+	return append(keys, sortKey{column: "id"})
+}
+
+// reverseKeys flips the direction of every key
+func reverseKeys(keys []sortKey) []sortKey {
+	reversed := make([]sortKey, len(keys))
+	for i, k := range keys {
+		reversed[i] = sortKey{column: k.column, desc: !k.desc}
+	}
+	return reversed
+}
+
+// orderBy renders sort keys as an ORDER BY expression
+func orderBy(keys []sortKey) string {
+	terms := make([]string, len(keys))
+	for i, k := range keys {
+		direction := "ASC"
+		if k.desc {
+			direction = "DESC"
+		}
+		terms[i] = k.column + " " + direction
+	}
+	return strings.Join(terms, ", ")
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"user-api/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// TestListBirthdays_Postgres checks the birthday queries against
+// models.NextBirthday for every date of birth in and around a leap year,
+// over ranges that wrap past December and cross February 29
+func TestListBirthdays_Postgres(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	var users []models.NewUser
+	for dob := date(1999, 1, 1); dob.Year() < 2002; dob = dob.AddDate(0, 0, 1) {
+		users = append(users, models.NewUser{Name: dob.Format("2006-01-02"), DOB: dob})
+	}
+	// Born on the first day of a range, with no birthday until a year later
+	users = append(users, models.NewUser{Name: "newborn", DOB: date(2024, 12, 20)})
+	results, err := repo.BulkCreate(ctx, users, false)
+	if err != nil {
+		t.Fatalf("BulkCreate() error = %v", err)
+	}
+	dobs := make(map[int64]time.Time, len(results))
+	for i, result := range results {
+		dobs[result.ID] = users[i].DOB
+	}
+
+	// Deleted users have no birthdays
+	deleted := results[0].ID
+	if err := repo.Delete(ctx, deleted, nil); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	ranges := []struct{ from, to time.Time }{
+		{date(2024, 1, 1), date(2024, 1, 31)},
+		{date(2024, 2, 27), date(2024, 3, 2)},
+		{date(2023, 2, 27), date(2023, 3, 2)},
+		{date(2024, 12, 20), date(2025, 1, 10)},
+		{date(2024, 3, 1), date(2025, 2, 28)},
+		{date(2023, 3, 1), date(2024, 2, 29)},
+		// 366 dates without a February 29 hold two June 1 birthdays
+		{date(2022, 6, 1), date(2023, 6, 1)},
+		// Users born during the range are listed from their first birthday
+		{date(2000, 6, 1), date(2001, 5, 31)},
+	}
+	for _, r := range ranges {
+		name := r.from.Format("2006-01-02") + " to " + r.to.Format("2006-01-02")
+		t.Run(name, func(t *testing.T) {
+			var expected []int64
+			for id, dob := range dobs {
+				if id == deleted {
+					continue
+				}
+				if birthday := models.NextBirthday(dob, r.from); !birthday.After(r.to) && birthday.After(dob) {
+					expected = append(expected, id)
+				}
+			}
+
+			count, err := repo.CountBirthdays(ctx, r.from, r.to)
+			if err != nil {
+				t.Fatalf("CountBirthdays() error = %v", err)
+			}
+			if count != int64(len(expected)) {
+				t.Errorf("CountBirthdays() = %d, expected %d", count, len(expected))
+			}
+
+			birthdays, err := repo.ListBirthdays(ctx, models.BirthdayListOptions{From: r.from, To: r.to, Limit: len(dobs), Offset: 0})
+			if err != nil {
+				t.Fatalf("ListBirthdays() error = %v", err)
+			}
+			if len(birthdays) != len(expected) {
+				t.Fatalf("ListBirthdays() returned %d users, expected %d", len(birthdays), len(expected))
+			}
+			for i, b := range birthdays {
+				if want := models.NextBirthday(b.User.DOB, r.from); !b.Birthday.Equal(want) {
+					t.Errorf("birthday of %s = %s, expected %s", b.User.Name, b.Birthday.Format("2006-01-02"), want.Format("2006-01-02"))
+				}
+				if i > 0 {
+					prev := birthdays[i-1]
+					if b.Birthday.Before(prev.Birthday) || (b.Birthday.Equal(prev.Birthday) && b.User.ID < prev.User.ID) {
+						t.Errorf("%s listed after %s, expected ordering by birthday and ID", b.User.Name, prev.User.Name)
+					}
+				}
+			}
+
+			// Pages split the same ordering
+			if len(birthdays) > 3 {
+				page, err := repo.ListBirthdays(ctx, models.BirthdayListOptions{From: r.from, To: r.to, Limit: 2, Offset: 2})
+				if err != nil {
+					t.Fatalf("ListBirthdays() error = %v", err)
+				}
+				if len(page) != 2 || page[0].User.ID != birthdays[2].User.ID || page[1].User.ID != birthdays[3].User.ID {
+					t.Errorf("page at offset 2 = %+v, expected the third and fourth birthdays", page)
+				}
+			}
+		})
+	}
+}
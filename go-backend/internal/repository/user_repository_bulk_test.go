@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"user-api/internal/models"
+)
+
+// TestBulkCreate_BestEffort_Postgres checks that a failing batch is retried
+// row by row, so only the rows Postgres rejects fail and the rest of their
+// batch is kept
+func TestBulkCreate_BestEffort_Postgres(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	users := make([]models.NewUser, bulkBatchSize+10)
+	for i := range users {
+		users[i] = models.NewUser{Name: "user-" + strconv.Itoa(i), DOB: date(1990, 1, 1).AddDate(0, 0, i)}
+	}
+	// Text cannot hold NUL bytes, so these rows fail in the first batch
+	bad := map[int]bool{3: true, 42: true}
+	for i := range bad {
+		users[i].Name = "bad\x00name"
+	}
+
+	results, err := repo.BulkCreate(ctx, users, true)
+	if err != nil {
+		t.Fatalf("BulkCreate() error = %v", err)
+	}
+
+	seen := make(map[int64]bool, len(results))
+	for i, result := range results {
+		if bad[i] {
+			if result.Err == nil {
+				t.Errorf("results[%d].Err = nil, expected the insert error", i)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Fatalf("results[%d].Err = %v", i, result.Err)
+		}
+		if seen[result.ID] {
+			t.Errorf("results[%d].ID = %d was already reported for another row", i, result.ID)
+		}
+		seen[result.ID] = true
+
+		user, err := repo.GetByID(ctx, result.ID, false)
+		if err != nil {
+			t.Fatalf("GetByID(%d) error = %v", result.ID, err)
+		}
+		// Each id must belong to the row it is reported against
+		if user.Name != users[i].Name || !user.DOB.Equal(users[i].DOB) {
+			t.Errorf("results[%d].ID = %d is user %s born %v, expected %s born %v", i, result.ID, user.Name, user.DOB, users[i].Name, users[i].DOB)
+		}
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	if count != len(users)-len(bad) {
+		t.Errorf("users = %d, expected %d", count, len(users)-len(bad))
+	}
+
+	// Outside best-effort mode the whole import is rolled back
+	if _, err := repo.BulkCreate(ctx, users, false); err == nil {
+		t.Error("BulkCreate() error = nil, expected the insert error")
+	}
+	var after int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&after); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	if after != count {
+		t.Errorf("users after failed import = %d, expected %d", after, count)
+	}
+}
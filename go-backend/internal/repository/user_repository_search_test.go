@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"user-api/internal/models"
+)
+
+// TestNameSearchIndexes_Postgres checks that the name filters can use the
+// indexes from migration 005 under the database's default collation
+func TestNameSearchIndexes_Postgres(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		filter models.UserFilter
+		index  string
+	}{
+		{"prefix", models.UserFilter{NamePrefix: "Al", IncludeDeleted: true}, "idx_users_name_pattern"},
+		{"contains", models.UserFilter{NameContains: "lic", IncludeDeleted: true}, "idx_users_name_trgm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTx() error = %v", err)
+			}
+			defer tx.Rollback()
+
+			// The table is tiny, so rule out the sequential scan the
+			// planner would otherwise prefer
+			if _, err := tx.ExecContext(ctx, `SET LOCAL enable_seqscan = off`); err != nil {
+				t.Fatalf("SET enable_seqscan error = %v", err)
+			}
+
+			qb := &queryBuilder{}
+			where := strings.Join(qb.conditions(tt.filter), " AND ")
+			rows, err := tx.QueryContext(ctx, "EXPLAIN SELECT id FROM users WHERE "+where, qb.args...)
+			if err != nil {
+				t.Fatalf("EXPLAIN error = %v", err)
+			}
+			defer rows.Close()
+
+			var plan strings.Builder
+			for rows.Next() {
+				var line string
+				if err := rows.Scan(&line); err != nil {
+					t.Fatalf("Scan() error = %v", err)
+				}
+				plan.WriteString(line + "\n")
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("rows error = %v", err)
+			}
+			if !strings.Contains(plan.String(), tt.index) {
+				t.Errorf("plan does not use %s:\n%s", tt.index, plan.String())
+			}
+		})
+	}
+}
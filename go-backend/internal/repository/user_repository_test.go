@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"user-api/db/sqlc"
+	"user-api/internal/models"
+)
+
+func TestQueryBuilderConditions(t *testing.T) {
+	minAge, maxAge := 18, 30
+
+	qb := &queryBuilder{}
+	clause := where(qb.conditions(models.UserFilter{
+		NameContains: "50%_off",
+		NamePrefix:   "Al",
+		MinAge:       &minAge,
+		MaxAge:       &maxAge,
+		AgeOn:        time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC),
+	}))
+
+	expected := "WHERE deleted_at IS NULL AND name ILIKE $1 AND name LIKE $2 AND dob <= $3 AND dob > $4"
+	if clause != expected {
+		t.Fatalf("where() = %q, expected %q", clause, expected)
+	}
+	if qb.args[0] != `%50\%\_off%` {
+		t.Errorf("Expected escaped substring pattern, got %v", qb.args[0])
+	}
+	if qb.args[1] != "Al%" {
+		t.Errorf("Expected prefix pattern Al%%, got %v", qb.args[1])
+	}
+	if got := qb.args[2].(time.Time); !got.Equal(time.Date(2006, 6, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected min_age bound 2006-06-15, got %v", got)
+	}
+	if got := qb.args[3].(time.Time); !got.Equal(time.Date(1993, 6, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected max_age bound 1993-06-15, got %v", got)
+	}
+}
+
+func TestQueryBuilderConditions_IncludeDeleted(t *testing.T) {
+	qb := &queryBuilder{}
+	if clause := where(qb.conditions(models.UserFilter{IncludeDeleted: true})); clause != "" {
+		t.Errorf("Expected empty WHERE clause, got %q", clause)
+	}
+	if len(qb.args) != 0 {
+		t.Errorf("Expected no args, got %d", len(qb.args))
+	}
+
+	if clause := where((&queryBuilder{}).conditions(models.UserFilter{})); clause != "WHERE deleted_at IS NULL" {
+		t.Errorf("Expected deleted users to be excluded by default, got %q", clause)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		sort     []models.SortField
+		expected string
+	}{
+		{"default", nil, "id ASC"},
+		{"multi-key", []models.SortField{{Field: "name"}, {Field: "dob", Desc: true}}, "name ASC, dob DESC, id ASC"},
+		{"explicit id", []models.SortField{{Field: "id", Desc: true}, {Field: "name"}}, "id DESC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderBy(sortKeys(tt.sort)); got != tt.expected {
+				t.Errorf("orderBy() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderSeek(t *testing.T) {
+	keys := sortKeys([]models.SortField{{Field: "name"}, {Field: "dob", Desc: true}})
+	dob := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	qb := &queryBuilder{}
+	got, err := qb.seek(keys, &models.Keyset{Values: []interface{}{"Alice", dob}, ID: 7})
+	if err != nil {
+		t.Fatalf("seek() error = %v", err)
+	}
+	expected := "((name > $1) OR (name = $1 AND dob < $2) OR (name = $1 AND dob = $2 AND id > $3))"
+	if got != expected {
+		t.Errorf("seek() = %q, expected %q", got, expected)
+	}
+
+	qb = &queryBuilder{}
+	got, err = qb.seek(keys, &models.Keyset{Values: []interface{}{"Alice", dob}, ID: 7, Backward: true})
+	if err != nil {
+		t.Fatalf("seek() error = %v", err)
+	}
+	expected = "((name < $1) OR (name = $1 AND dob > $2) OR (name = $1 AND dob = $2 AND id < $3))"
+	if got != expected {
+		t.Errorf("seek() backward = %q, expected %q", got, expected)
+	}
+
+	if _, err := (&queryBuilder{}).seek(keys, &models.Keyset{ID: 7}); err != ErrInvalidKeyset {
+		t.Errorf("seek() with missing values error = %v, expected ErrInvalidKeyset", err)
+	}
+}
+
+func TestToUser(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	row := sqlc.User{
+		ID:        7,
+		Name:      "Alice",
+		Dob:       time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC),
+		CreatedAt: sql.NullTime{Time: created, Valid: true},
+		UpdatedAt: sql.NullTime{Time: created, Valid: true},
+	}
+
+	user := toUser(row)
+	if user.ID != 7 || user.Name != "Alice" || !user.DOB.Equal(row.Dob) || !user.UpdatedAt.Equal(created) {
+		t.Errorf("toUser() = %+v", user)
+	}
+	if user.DeletedAt != nil {
+		t.Errorf("toUser() DeletedAt = %v, expected nil", user.DeletedAt)
+	}
+
+	row.DeletedAt = sql.NullTime{Time: created, Valid: true}
+	if user := toUser(row); user.DeletedAt == nil || !user.DeletedAt.Equal(created) {
+		t.Errorf("toUser() DeletedAt = %v, expected %v", user.DeletedAt, created)
+	}
+}
+
+// shuffledQuerier returns inserted users out of input order, as Postgres is
+// free to
+type shuffledQuerier struct {
+	sqlc.Querier
+}
+
+func (shuffledQuerier) CreateUsers(ctx context.Context, arg sqlc.CreateUsersParams) ([]sqlc.CreateUsersRow, error) {
+	rows := make([]sqlc.CreateUsersRow, len(arg.Names))
+	for i := range arg.Names {
+		// Ids descend while ordinals ascend, and rows come back reversed
+		rows[len(rows)-1-i] = sqlc.CreateUsersRow{Ord: int64(i + 1), ID: int64(100 - i)}
+	}
+	return rows, nil
+}
+
+func TestInsertBatch_MapsIDsByOrdinal(t *testing.T) {
+	users := []models.NewUser{{Name: "Alice"}, {Name: "Bob"}, {Name: "Carol"}}
+
+	ids, err := insertBatch(context.Background(), shuffledQuerier{}, users)
+	if err != nil {
+		t.Fatalf("insertBatch() error = %v", err)
+	}
+	if expected := []int64{100, 99, 98}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("insertBatch() = %v, expected %v", ids, expected)
+	}
+}
@@ -1,28 +1,106 @@
 package routes
 
 import (
+	"slices"
+
 	"github.com/gofiber/fiber/v2"
 
+	"user-api/docs"
 	"user-api/internal/handler"
+	"user-api/internal/middleware"
+	"user-api/internal/rbac"
 )
 
-// SetupRoutes configures all API routes
-func SetupRoutes(app *fiber.App, userHandler *handler.UserHandler) {
-	// Health check endpoint
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "healthy",
-		})
+// RouteNames lists the names routes are rate limited under, as used in
+// RATE_LIMIT_ROUTES
+var RouteNames = []string{
+	"users.create", "users.list", "users.get", "users.update", "users.patch",
+	"users.delete", "users.restore", "users.import", "users.export",
+	"users.birthdays", "api_keys",
+}
+
+// SetupRoutes configures all API routes. Every route requires the
+// permission for its action; a nil authorizer disables role checks. Each
+// route is rate limited under its name, for example "users.list"; a nil
+// limiter disables rate limiting. POST routes then run idempotency, so a
+// stored response is only replayed to callers who pass both checks; a nil
+// idempotency handler disables it.
+func SetupRoutes(app *fiber.App, userHandler *handler.UserHandler, apiKeyHandler *handler.APIKeyHandler, healthHandler *handler.HealthHandler, authz *middleware.Authorizer, limiter *middleware.RateLimiter, idempotency fiber.Handler) {
+	// Probes
+	app.Get("/livez", healthHandler.Livez)
+	app.Get("/readyz", healthHandler.Readyz)
+	app.Get("/startupz", healthHandler.Startupz)
+
+	// API documentation; docs/openapi.json must describe every route below
+	app.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Type("json")
+		return c.Send(docs.OpenAPI)
+	})
+	app.Get("/docs", func(c *fiber.Ctx) error {
+		c.Type("html")
+		return c.Send(docs.UI)
 	})
 
 	// API v1 routes
 	api := app.Group("/api/v1")
 
+	// Every name must be listed in RouteNames so RATE_LIMIT_ROUTES can be
+	// checked against it
+	limit := func(name string) fiber.Handler {
+		if !slices.Contains(RouteNames, name) {
+			panic("routes: rate limit name " + name + " is missing from RouteNames")
+		}
+		return limiter.Limit(name)
+	}
+	idempotent := idempotency
+	if idempotent == nil {
+		idempotent = func(c *fiber.Ctx) error { return c.Next() }
+	}
+
+	read := authz.Require(rbac.PermUsersRead)
+	write := authz.Require(rbac.PermUsersWrite)
+	remove := authz.Require(rbac.PermUsersDelete)
+	// Only callers who may delete users may see deleted ones, and nobody
+	// without an API key may while role-based access control is disabled
+	deleted := includingDeleted(authz.RequireStrict(rbac.PermUsersDelete))
+
+	// Collection actions use the users:action form, the colon is escaped
+	api.Post("/users\\:import", limit("users.import"), write, idempotent, userHandler.ImportUsers)
+	api.Get("/users\\:export", limit("users.export"), read, deleted, userHandler.ExportUsers)
+
 	// User routes
 	users := api.Group("/users")
-	users.Post("/", userHandler.CreateUser)
-	users.Get("/", userHandler.ListUsers)
-	users.Get("/:id", userHandler.GetUser)
-	users.Put("/:id", userHandler.UpdateUser)
-	users.Delete("/:id", userHandler.DeleteUser)
+	users.Post("/", limit("users.create"), write, idempotent, userHandler.CreateUser)
+	users.Get("/", limit("users.list"), read, deleted, userHandler.ListUsers)
+	// Registered before /:id, which would otherwise match it
+	users.Get("/birthdays", limit("users.birthdays"), read, userHandler.ListBirthdays)
+	users.Get("/:id", limit("users.get"), read, deleted, userHandler.GetUser)
+	users.Put("/:id", limit("users.update"), write, userHandler.UpdateUser)
+	users.Patch("/:id", limit("users.patch"), write, userHandler.PatchUser)
+	users.Delete("/:id", limit("users.delete"), remove, userHandler.DeleteUser)
+	// Restoring undoes a delete, so it needs the same permission
+	users.Post("/:id/restore", limit("users.restore"), remove, idempotent, userHandler.RestoreUser)
+
+	// API key management stays closed unless role-based access control is
+	// enabled, or the caller's API key holds the permission
+	apiKeys := api.Group("/api-keys", limit("api_keys"), authz.RequireStrict(rbac.PermAPIKeysManage))
+	apiKeys.Post("/", idempotent, apiKeyHandler.CreateAPIKey)
+	apiKeys.Get("/", apiKeyHandler.ListAPIKeys)
+	apiKeys.Post("/:id/rotate", idempotent, apiKeyHandler.RotateAPIKey)
+	apiKeys.Delete("/:id", apiKeyHandler.RevokeAPIKey)
+}
+
+// includingDeleted runs check only for requests asking for deleted users
+// with include_deleted, parsed the way the handlers parse it
+func includingDeleted(check fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var query struct {
+			IncludeDeleted bool `query:"include_deleted"`
+		}
+		// A malformed value is checked too, the handler then rejects it
+		if err := c.QueryParser(&query); err == nil && !query.IncludeDeleted {
+			return c.Next()
+		}
+		return check(c)
+	}
 }
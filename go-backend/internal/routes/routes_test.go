@@ -0,0 +1,618 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user-api/docs"
+	"user-api/internal/handler"
+	"user-api/internal/health"
+	"user-api/internal/logger"
+	"user-api/internal/middleware"
+	"user-api/internal/models"
+	"user-api/internal/ratelimit"
+	"user-api/internal/rbac"
+	"user-api/internal/service"
+	"user-api/internal/validation"
+)
+
+// stubService answers the calls these tests make without a database.
+// Methods not overridden panic through the nil embedded interface.
+type stubService struct {
+	service.UserService
+}
+
+func (stubService) GetUser(ctx context.Context, id int64, query *models.GetUserQuery) (*models.UserResponse, error) {
+	return &models.UserResponse{ID: id, Name: "Alice", DOB: "1990-05-10", ETag: `"v1"`}, nil
+}
+
+func (stubService) DeleteUser(ctx context.Context, id int64, ifMatch string) error {
+	return nil
+}
+
+// PatchUser applies build to the user GetUser returns
+func (s stubService) PatchUser(ctx context.Context, id int64, build service.PatchFunc, ifMatch string) (*models.UserResponse, error) {
+	current, _ := s.GetUser(ctx, id, nil)
+	req, err := build(*current)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", service.ErrPatchRejected, err)
+	}
+	if req.Name != nil {
+		current.Name = *req.Name
+	}
+	return current, nil
+}
+
+func (stubService) ListBirthdays(ctx context.Context, query *models.BirthdaysQuery) (*models.BirthdaysResponse, error) {
+	return &models.BirthdaysResponse{From: "2024-06-15", To: "2024-07-15"}, nil
+}
+
+// stubAPIKeys mints keys without a database, rejecting scopes the caller
+// does not hold like the real service
+type stubAPIKeys struct {
+	service.APIKeyService
+}
+
+func (stubAPIKeys) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, held service.ScopeCheck) (*models.CreatedAPIKeyResponse, error) {
+	for _, scope := range req.Scopes {
+		if !held(scope) {
+			return nil, service.ErrScopeNotHeld
+		}
+	}
+	return &models.CreatedAPIKeyResponse{APIKeyResponse: models.APIKeyResponse{ID: 1, Name: req.Name, Scopes: req.Scopes}, Key: "secret"}, nil
+}
+
+// testValidator reports validation errors with the built-in translations
+var testValidator = func() *validation.Validator {
+	v, err := validation.New("")
+	if err != nil {
+		panic(err)
+	}
+	return v
+}()
+
+func newTestApp(authz *middleware.Authorizer) *fiber.App {
+	log := logger.NewLogger()
+	app := fiber.New()
+	SetupRoutes(app, handler.NewUserHandler(stubService{}, testValidator, log), handler.NewAPIKeyHandler(stubAPIKeys{}, testValidator, authz.Holds, log), handler.NewHealthHandler(health.NewChecker(time.Second)), authz, nil, nil)
+	return app
+}
+
+func TestSetupRoutes_RBAC(t *testing.T) {
+	authz := middleware.NewAuthorizer(rbac.DefaultPolicy(), middleware.RolesFromHeader("X-User-Role"), logger.NewLogger())
+	app := newTestApp(authz)
+
+	tests := []struct {
+		name       string
+		method     string
+		role       string
+		wantStatus int
+		wantReason string
+	}{
+		{"viewer reads", fiber.MethodGet, "viewer", fiber.StatusOK, ""},
+		{"viewer deletes", fiber.MethodDelete, "viewer", fiber.StatusForbidden, middleware.ReasonMissingPermission},
+		{"editor deletes", fiber.MethodDelete, "editor", fiber.StatusForbidden, middleware.ReasonMissingPermission},
+		{"admin deletes", fiber.MethodDelete, "admin", fiber.StatusNoContent, ""},
+		{"multiple roles", fiber.MethodDelete, "viewer, admin", fiber.StatusNoContent, ""},
+		{"no role", fiber.MethodGet, "", fiber.StatusForbidden, middleware.ReasonNoRole},
+		{"unknown role", fiber.MethodGet, "guest", fiber.StatusForbidden, middleware.ReasonMissingPermission},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/users/1", nil)
+			if tt.role != "" {
+				req.Header.Set("X-User-Role", tt.role)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, expected %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if tt.wantReason != "" {
+				var body struct {
+					Reason     string `json:"reason"`
+					Permission string `json:"permission"`
+				}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatalf("Failed to decode body: %v", err)
+				}
+				if body.Reason != tt.wantReason || body.Permission == "" {
+					t.Errorf("body = %+v, expected reason %q with a permission", body, tt.wantReason)
+				}
+			}
+		})
+	}
+}
+
+func TestSetupRoutes_NoAuthorizer(t *testing.T) {
+	app := newTestApp(nil)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/users/1", nil))
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("status = %d, expected %d", resp.StatusCode, fiber.StatusNoContent)
+	}
+}
+
+func TestSetupRoutes_NoAuthorizerClosesAPIKeys(t *testing.T) {
+	app := newTestApp(nil)
+
+	body := `{"name": "ci", "scopes": ["api_keys:manage", "users:delete"]}`
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/api-keys", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("POST /api/v1/api-keys status = %d, expected %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+	var problem struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if problem.Reason != middleware.ReasonRBACDisabled {
+		t.Errorf("reason = %q, expected %q", problem.Reason, middleware.ReasonRBACDisabled)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/api-keys", nil))
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("GET /api/v1/api-keys status = %d, expected %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+}
+
+func TestSetupRoutes_APIKeyScopesHeldByCaller(t *testing.T) {
+	policy := rbac.NewPolicy(map[string][]string{
+		"keymaster": {rbac.PermAPIKeysManage, rbac.PermUsersRead},
+	})
+	app := newTestApp(middleware.NewAuthorizer(policy, middleware.RolesFromHeader("X-User-Role"), logger.NewLogger()))
+
+	tests := []struct {
+		name       string
+		scopes     string
+		wantStatus int
+	}{
+		{"held scopes", `["users:read", "api_keys:manage"]`, fiber.StatusCreated},
+		{"scope not held", `["users:read", "users:delete"]`, fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/api-keys", strings.NewReader(`{"name": "ci", "scopes": `+tt.scopes+`}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			req.Header.Set("X-User-Role", "keymaster")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, expected %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSetupRoutes_IncludeDeleted(t *testing.T) {
+	authz := middleware.NewAuthorizer(rbac.DefaultPolicy(), middleware.RolesFromHeader("X-User-Role"), logger.NewLogger())
+
+	tests := []struct {
+		name       string
+		authz      *middleware.Authorizer
+		target     string
+		role       string
+		wantStatus int
+	}{
+		{"viewer reads", authz, "/api/v1/users/1", "viewer", fiber.StatusOK},
+		{"viewer reads deleted", authz, "/api/v1/users/1?include_deleted=true", "viewer", fiber.StatusForbidden},
+		{"viewer reads deleted with on", authz, "/api/v1/users/1?include_deleted=on", "viewer", fiber.StatusForbidden},
+		{"viewer exports deleted", authz, "/api/v1/users:export?include_deleted=1", "viewer", fiber.StatusForbidden},
+		{"viewer reads without deleted", authz, "/api/v1/users/1?include_deleted=false", "viewer", fiber.StatusOK},
+		{"admin reads deleted", authz, "/api/v1/users/1?include_deleted=true", "admin", fiber.StatusOK},
+		{"no authorizer", nil, "/api/v1/users/1", "", fiber.StatusOK},
+		{"no authorizer reads deleted", nil, "/api/v1/users/1?include_deleted=true", "", fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, tt.target, nil)
+			if tt.role != "" {
+				req.Header.Set("X-User-Role", tt.role)
+			}
+			resp, err := newTestApp(tt.authz).Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s status = %d, expected %d", tt.target, resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSetupRoutes_PatchReadOnly(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantDetail  string
+	}{
+		{"merge age", "application/merge-patch+json", `{"age": 40}`, fiber.StatusBadRequest, "age is read-only"},
+		{"merge unchanged id", "application/merge-patch+json", `{"id": 1, "name": "Bob"}`, fiber.StatusBadRequest, "id is read-only"},
+		{"json patch age_as_of", "application/json-patch+json", `[{"op": "remove", "path": "/age_as_of"}]`, fiber.StatusBadRequest, "age_as_of is read-only"},
+		{"json patch move from id", "application/json-patch+json", `[{"op": "move", "from": "/id", "path": "/name"}]`, fiber.StatusBadRequest, "id is read-only"},
+		{"json patch tests id", "application/json-patch+json", `[{"op": "test", "path": "/id", "value": 1}, {"op": "replace", "path": "/name", "value": "Bob"}]`, fiber.StatusOK, ""},
+		{"merge name", "application/merge-patch+json", `{"name": "Bob"}`, fiber.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPatch, "/api/v1/users/1", strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, tt.contentType)
+			resp, err := newTestApp(nil).Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("PATCH %s status = %d, expected %d", tt.body, resp.StatusCode, tt.wantStatus)
+			}
+			var problem struct {
+				Detail string `json:"detail"`
+			}
+			json.NewDecoder(resp.Body).Decode(&problem)
+			if tt.wantDetail != "" && problem.Detail != tt.wantDetail {
+				t.Errorf("PATCH %s detail = %q, expected %q", tt.body, problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+// stubKeys authenticates the single key "read-only-key" with read scope
+type stubKeys struct{}
+
+func (stubKeys) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	if rawKey != "read-only-key" {
+		return nil, service.ErrInvalidAPIKey
+	}
+	return &models.APIKey{ID: 1, Prefix: "0123456789ab", Scopes: []string{rbac.PermUsersRead}}, nil
+}
+
+func TestSetupRoutes_APIKeyScopes(t *testing.T) {
+	log := logger.NewLogger()
+	app := fiber.New()
+	app.Use(middleware.APIKeyAuth(stubKeys{}, log))
+	// Scopes apply even when role-based access control is disabled
+	SetupRoutes(app, handler.NewUserHandler(stubService{}, testValidator, log), handler.NewAPIKeyHandler(nil, testValidator, nil, log), handler.NewHealthHandler(health.NewChecker(time.Second)), nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		key        string
+		wantStatus int
+	}{
+		{"read scope reads", fiber.MethodGet, "/api/v1/users/1", "read-only-key", fiber.StatusOK},
+		{"read scope deletes", fiber.MethodDelete, "/api/v1/users/1", "read-only-key", fiber.StatusForbidden},
+		{"read scope manages keys", fiber.MethodGet, "/api/v1/api-keys", "read-only-key", fiber.StatusForbidden},
+		{"invalid key", fiber.MethodGet, "/api/v1/users/1", "wrong-key", fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(middleware.HeaderAPIKey, tt.key)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, expected %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSetupRoutes_RateLimit(t *testing.T) {
+	log := logger.NewLogger()
+	limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{}, map[string]ratelimit.Limit{
+		"users.delete": {Requests: 2, Period: time.Minute},
+	}, log)
+	app := fiber.New()
+	app.Use(middleware.APIKeyAuth(stubKeys{}, log))
+	SetupRoutes(app, handler.NewUserHandler(stubService{}, testValidator, log), handler.NewAPIKeyHandler(nil, testValidator, nil, log), handler.NewHealthHandler(health.NewChecker(time.Second)), nil, limiter, nil)
+
+	do := func(method, key string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/v1/users/1", nil)
+		if key != "" {
+			req.Header.Set(middleware.HeaderAPIKey, key)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		return resp
+	}
+
+	for i := 1; i >= 0; i-- {
+		resp := do(fiber.MethodDelete, "")
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("status = %d, expected %d", resp.StatusCode, fiber.StatusNoContent)
+		}
+		if got := resp.Header.Get(middleware.HeaderRateLimitRemaining); got != strconv.Itoa(i) {
+			t.Errorf("RateLimit-Remaining = %q, expected %d", got, i)
+		}
+	}
+
+	resp := do(fiber.MethodDelete, "")
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("status = %d, expected %d", resp.StatusCode, fiber.StatusTooManyRequests)
+	}
+	if got := resp.Header.Get(fiber.HeaderRetryAfter); got != "30" {
+		t.Errorf("Retry-After = %q, expected 30", got)
+	}
+	if got := resp.Header.Get(middleware.HeaderRateLimitPolicy); got != "2;w=60" {
+		t.Errorf("RateLimit-Policy = %q, expected 2;w=60", got)
+	}
+
+	// Routes without a limit and other clients are unaffected
+	if resp := do(fiber.MethodGet, ""); resp.StatusCode != fiber.StatusOK || resp.Header.Get(middleware.HeaderRateLimitLimit) != "" {
+		t.Errorf("unlimited route status = %d, headers %v", resp.StatusCode, resp.Header)
+	}
+	if resp := do(fiber.MethodDelete, "read-only-key"); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("API key client status = %d, expected its own bucket and %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+}
+
+// replayingStore holds a stored 201 response for every idempotency key
+type replayingStore struct{}
+
+func (replayingStore) Claim(ctx context.Context, scope, key string, fingerprint []byte, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	return &models.IdempotencyRecord{Fingerprint: fingerprint, StatusCode: fiber.StatusCreated, Body: []byte("stored")}, false, nil
+}
+
+func (replayingStore) Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error {
+	return nil
+}
+
+func (replayingStore) Release(ctx context.Context, scope, key string) error {
+	return nil
+}
+
+func TestSetupRoutes_IdempotencyAfterChecks(t *testing.T) {
+	log := logger.NewLogger()
+	authz := middleware.NewAuthorizer(rbac.DefaultPolicy(), middleware.RolesFromHeader("X-User-Role"), log)
+	limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{}, map[string]ratelimit.Limit{
+		"users.create": {Requests: 2, Period: time.Hour},
+	}, log)
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalsSubject, "alice")
+		return c.Next()
+	})
+	SetupRoutes(app, handler.NewUserHandler(stubService{}, testValidator, log), handler.NewAPIKeyHandler(nil, testValidator, nil, log), handler.NewHealthHandler(health.NewChecker(time.Second)), authz, limiter, middleware.Idempotency(replayingStore{}, time.Hour, log))
+
+	do := func(role string) (*http.Response, string) {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/users", strings.NewReader(`{"name": "Alice", "dob": "1990-05-10"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+		req.Header.Set("X-User-Role", role)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	// A caller who lost the permission gets no replay. Denied requests
+	// still take from the bucket, since limits apply first.
+	if resp, body := do("viewer"); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("viewer status = %d %s, expected %d", resp.StatusCode, body, fiber.StatusForbidden)
+	}
+	if resp, body := do("editor"); resp.StatusCode != fiber.StatusCreated || body != "stored" {
+		t.Errorf("editor = %d %s, expected the stored response", resp.StatusCode, body)
+	}
+	// Replays count against the rate limit
+	if resp, body := do("editor"); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("over quota status = %d %s, expected %d", resp.StatusCode, body, fiber.StatusTooManyRequests)
+	}
+}
+
+// pathParam matches a Fiber route parameter such as /:id; escaped colons
+// like the one in /users\:export are not preceded by a slash
+var pathParam = regexp.MustCompile(`/:(\w+)`)
+
+// openAPIPath converts a Fiber route path to its OpenAPI form
+func openAPIPath(path string) string {
+	path = pathParam.ReplaceAllString(path, "/{$1}")
+	path = strings.ReplaceAll(path, "\\:", ":")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	app := newTestApp(nil)
+
+	registered := map[string]bool{}
+	for _, route := range app.GetRoutes(true) {
+		// HEAD is added automatically for every GET; /docs renders the spec
+		if route.Method == fiber.MethodHead || route.Path == "/docs" {
+			continue
+		}
+		registered[route.Method+" "+openAPIPath(route.Path)] = true
+	}
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(docs.OpenAPI, &spec); err != nil {
+		t.Fatalf("Failed to parse openapi.json: %v", err)
+	}
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q, expected 3.1.0", spec.OpenAPI)
+	}
+
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" || method == "summary" || method == "description" {
+				continue
+			}
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var missing, stale []string
+	for op := range registered {
+		if !documented[op] {
+			missing = append(missing, op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			stale = append(stale, op)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from openapi.json: %v", missing)
+	}
+	if len(stale) > 0 {
+		t.Errorf("openapi.json documents unregistered routes: %v", stale)
+	}
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	app := newTestApp(nil)
+
+	for path, contentType := range map[string]string{"/openapi.json": fiber.MIMEApplicationJSON, "/docs": fiber.MIMETextHTML} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK || !strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), contentType) {
+			t.Errorf("GET %s = %d %q, expected 200 %s", path, resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), contentType)
+		}
+	}
+}
+
+func TestSetupRoutes_Probes(t *testing.T) {
+	log := logger.NewLogger()
+	checker := health.NewChecker(time.Second)
+	checker.Register("database", func(ctx context.Context) (map[string]any, error) {
+		return map[string]any{"in_use": 1}, nil
+	})
+	app := fiber.New()
+	SetupRoutes(app, handler.NewUserHandler(stubService{}, testValidator, log), handler.NewAPIKeyHandler(nil, testValidator, nil, log), handler.NewHealthHandler(checker), nil, nil, nil)
+
+	probe := func(path string) (int, health.Report) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		var report health.Report
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to decode body: %v", err)
+		}
+		return resp.StatusCode, report
+	}
+
+	if status, report := probe("/readyz"); status != fiber.StatusOK || report.Status != health.StatusOK || report.Checks != nil {
+		t.Errorf("/readyz = %d %+v, expected 200 without checks", status, report)
+	}
+	if status, report := probe("/readyz?verbose"); status != fiber.StatusOK || len(report.Checks) != 1 || report.Checks[0].Name != "database" {
+		t.Errorf("/readyz?verbose = %d %+v, expected the database check", status, report)
+	}
+
+	checker.Drain()
+	if status, report := probe("/readyz"); status != fiber.StatusServiceUnavailable || report.Status != health.StatusDraining {
+		t.Errorf("/readyz while draining = %d %+v, expected 503 draining", status, report)
+	}
+	for _, path := range []string{"/livez", "/startupz"} {
+		if status, _ := probe(path); status != fiber.StatusOK {
+			t.Errorf("%s while draining = %d, expected 200", path, status)
+		}
+	}
+}
+
+func TestSetupRoutes_LocalisedValidation(t *testing.T) {
+	app := newTestApp(nil)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/users", strings.NewReader(`{"name": "", "dob": "10/05/1990"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(fiber.HeaderAcceptLanguage, "de-CH, en;q=0.5")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest || resp.Header.Get(fiber.HeaderContentLanguage) != "de" {
+		t.Fatalf("POST /api/v1/users = %d, Content-Language %q, expected 400 de", resp.StatusCode, resp.Header.Get(fiber.HeaderContentLanguage))
+	}
+
+	var body struct {
+		InvalidParams []struct {
+			Name   string `json:"name"`
+			Reason string `json:"reason"`
+		} `json:"invalid_params"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if len(body.InvalidParams) != 2 || body.InvalidParams[1].Name != "name" || body.InvalidParams[1].Reason != "name ist erforderlich" {
+		t.Errorf("invalid_params = %+v, expected German reasons for dob and name", body.InvalidParams)
+	}
+}
+
+func TestSetupRoutes_Birthdays(t *testing.T) {
+	app := newTestApp(nil)
+
+	// /users/birthdays must not be taken for a user ID
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/users/birthdays?within_days=30", nil))
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	var body models.BirthdaysResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || body.From != "2024-06-15" {
+		t.Errorf("GET /api/v1/users/birthdays = %d %+v, expected 200 with the range", resp.StatusCode, body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/users/birthdays?within_days=400", nil))
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("GET /api/v1/users/birthdays?within_days=400 = %d, expected 400", resp.StatusCode)
+	}
+}
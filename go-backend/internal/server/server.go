@@ -0,0 +1,58 @@
+// Package server runs the Fiber app until shutdown and drains in-flight
+// requests before returning.
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"user-api/internal/health"
+	"user-api/internal/logger"
+)
+
+// Config holds the shutdown timings
+type Config struct {
+	// DrainDelay is how long the server keeps accepting requests after
+	// readiness starts failing, giving load balancers time to notice
+	DrainDelay time.Duration
+	// Timeout bounds how long in-flight requests may run once the server
+	// stops accepting connections
+	Timeout time.Duration
+}
+
+// Run serves app on ln until ctx is done, then marks checker as draining,
+// waits DrainDelay, stops accepting connections and waits up to Timeout for
+// in-flight requests to complete. checker may be nil. Run returns an error
+// if the server fails or requests are still running after Timeout.
+func Run(ctx context.Context, app *fiber.App, ln net.Listener, checker *health.Checker, cfg Config, log *logger.Logger) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Listener(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Draining before shutdown", zap.Duration("delay", cfg.DrainDelay), zap.Duration("timeout", cfg.Timeout))
+	if checker != nil {
+		checker.Drain()
+	}
+	time.Sleep(cfg.DrainDelay)
+
+	if err := app.ShutdownWithTimeout(cfg.Timeout); err != nil {
+		return fmt.Errorf("failed to drain in-flight requests: %w", err)
+	}
+	if err := <-served; err != nil {
+		return err
+	}
+	log.Info("Server stopped")
+	return nil
+}
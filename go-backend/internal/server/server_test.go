@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user-api/internal/health"
+	"user-api/internal/logger"
+)
+
+// testServer runs an app whose /slow route blocks until release is closed
+type testServer struct {
+	addr    string
+	checker *health.Checker
+	started chan struct{}
+	release chan struct{}
+	stop    context.CancelFunc
+	done    chan error
+}
+
+func startServer(t *testing.T, cfg Config) *testServer {
+	t.Helper()
+	ts := &testServer{
+		checker: health.NewChecker(time.Second),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+		done:    make(chan error, 1),
+	}
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		close(ts.started)
+		<-ts.release
+		return c.SendString("done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	ts.addr = ln.Addr().String()
+
+	var ctx context.Context
+	ctx, ts.stop = context.WithCancel(context.Background())
+	go func() {
+		ts.done <- Run(ctx, app, ln, ts.checker, cfg, logger.NewLogger())
+	}()
+	return ts
+}
+
+func TestRun_CompletesInFlightRequest(t *testing.T) {
+	ts := startServer(t, Config{Timeout: 5 * time.Second})
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ts.addr + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+
+	<-ts.started
+	ts.stop()
+
+	// Shutdown waits for the request instead of returning
+	select {
+	case err := <-ts.done:
+		t.Fatalf("Run() returned %v with a request in flight", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !ts.checker.Draining() {
+		t.Error("checker is not draining after shutdown started")
+	}
+	if _, err := net.DialTimeout("tcp", ts.addr, 100*time.Millisecond); err == nil {
+		t.Error("new connection accepted after shutdown started")
+	}
+
+	close(ts.release)
+	if got := <-responses; got.err != nil || got.body != "done" {
+		t.Errorf("in-flight response = %q, %v, expected done", got.body, got.err)
+	}
+	if err := <-ts.done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	ts := startServer(t, Config{Timeout: 50 * time.Millisecond})
+	defer close(ts.release)
+
+	go http.Get("http://" + ts.addr + "/slow")
+	<-ts.started
+	ts.stop()
+
+	select {
+	case err := <-ts.done:
+		if err == nil {
+			t.Error("Run() error = nil, expected the drain to time out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after the timeout")
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"user-api/internal/apikey"
+	"user-api/internal/logger"
+	"user-api/internal/models"
+	"user-api/internal/repository"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrScopeNotHeld rejects minting a key with a scope the caller lacks
+	ErrScopeNotHeld = errors.New("scope not held by caller")
+)
+
+// ScopeCheck reports whether the caller holds a scope and may therefore
+// grant it to a key
+type ScopeCheck func(scope string) bool
+
+// APIKeyService defines the interface for API key business logic
+type APIKeyService interface {
+	// CreateAPIKey and RotateAPIKey give ErrScopeNotHeld when held rejects
+	// any scope of the key
+	CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, held ScopeCheck) (*models.CreatedAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKeyResponse, error)
+	RotateAPIKey(ctx context.Context, id int64, held ScopeCheck) (*models.CreatedAPIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	// Authenticate resolves a presented key to its active record, recording
+	// the use. Unknown, revoked or malformed keys give ErrInvalidAPIKey.
+	Authenticate(ctx context.Context, rawKey string) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	repo   repository.APIKeyRepository
+	logger *logger.Logger
+}
+
+// NewAPIKeyService creates a new APIKeyService instance
+func NewAPIKeyService(repo repository.APIKeyRepository, logger *logger.Logger) APIKeyService {
+	return &apiKeyService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *apiKeyService) log(ctx context.Context) *logger.Logger {
+	return s.logger.FromContext(ctx)
+}
+
+// CreateAPIKey mints a new key and returns it with its plaintext
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, held ScopeCheck) (*models.CreatedAPIKeyResponse, error) {
+	s.log(ctx).Info("Creating API key", zap.String("name", req.Name), zap.Strings("scopes", req.Scopes))
+
+	if err := s.checkScopes(ctx, req.Scopes, held); err != nil {
+		return nil, err
+	}
+
+	key, err := apikey.Generate()
+	if err != nil {
+		s.log(ctx).Error("Failed to generate API key", zap.Error(err))
+		return nil, err
+	}
+
+	record, err := s.repo.Create(ctx, req.Name, key.Prefix, key.Salt, key.Hash, req.Scopes)
+	if err != nil {
+		s.log(ctx).Error("Failed to create API key", zap.Error(err))
+		return nil, err
+	}
+
+	s.log(ctx).Info("API key created", zap.Int64("api_key_id", record.ID), zap.String("prefix", record.Prefix))
+	return &models.CreatedAPIKeyResponse{APIKeyResponse: record.ToResponse(), Key: key.Plaintext}, nil
+}
+
+// ListAPIKeys lists every key, without secrets
+func (s *apiKeyService) ListAPIKeys(ctx context.Context) ([]models.APIKeyResponse, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		s.log(ctx).Error("Failed to list API keys", zap.Error(err))
+		return nil, err
+	}
+
+	responses := make([]models.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = key.ToResponse()
+	}
+	return responses, nil
+}
+
+// RotateAPIKey revokes a key and mints its replacement with the same name
+// and scopes
+func (s *apiKeyService) RotateAPIKey(ctx context.Context, id int64, held ScopeCheck) (*models.CreatedAPIKeyResponse, error) {
+	s.log(ctx).Info("Rotating API key", zap.Int64("api_key_id", id))
+
+	// Rotation hands out a fresh plaintext, so it is as powerful as minting
+	// a key with the same scopes
+	old, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.log(ctx).Warn("Failed to rotate API key", zap.Int64("api_key_id", id), zap.Error(err))
+		return nil, err
+	}
+	if err := s.checkScopes(ctx, old.Scopes, held); err != nil {
+		return nil, err
+	}
+
+	key, err := apikey.Generate()
+	if err != nil {
+		s.log(ctx).Error("Failed to generate API key", zap.Error(err))
+		return nil, err
+	}
+
+	record, err := s.repo.Rotate(ctx, id, key.Prefix, key.Salt, key.Hash)
+	if err != nil {
+		s.log(ctx).Warn("Failed to rotate API key", zap.Int64("api_key_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	s.log(ctx).Info("API key rotated", zap.Int64("api_key_id", id), zap.Int64("new_api_key_id", record.ID))
+	return &models.CreatedAPIKeyResponse{APIKeyResponse: record.ToResponse(), Key: key.Plaintext}, nil
+}
+
+// checkScopes rejects scopes the caller does not hold, so a key never
+// carries more than the caller who minted it
+func (s *apiKeyService) checkScopes(ctx context.Context, scopes []string, held ScopeCheck) error {
+	for _, scope := range scopes {
+		if !held(scope) {
+			s.log(ctx).Warn("API key scope not held by caller", zap.String("scope", scope))
+			return fmt.Errorf("%w: %s", ErrScopeNotHeld, scope)
+		}
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes a key so it can no longer authenticate
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id int64) error {
+	s.log(ctx).Info("Revoking API key", zap.Int64("api_key_id", id))
+
+	if _, err := s.repo.Revoke(ctx, id); err != nil {
+		s.log(ctx).Warn("Failed to revoke API key", zap.Int64("api_key_id", id), zap.Error(err))
+		return err
+	}
+
+	s.log(ctx).Info("API key revoked", zap.Int64("api_key_id", id))
+	return nil
+}
+
+// Authenticate verifies a presented key
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	prefix, secret, err := apikey.Parse(rawKey)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		s.log(ctx).Error("Failed to look up API key", zap.Error(err))
+		return nil, err
+	}
+	if !key.Active() || !apikey.Verify(key.Salt, key.Hash, secret) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// A failed usage stamp must not fail the request
+	if err := s.repo.TouchLastUsed(ctx, key.ID); err != nil {
+		s.log(ctx).Warn("Failed to record API key use", zap.Int64("api_key_id", key.ID), zap.Error(err))
+	}
+
+	return key, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"user-api/internal/logger"
+	"user-api/internal/models"
+	"user-api/internal/repository"
+)
+
+// fakeAPIKeyRepository is an in-memory APIKeyRepository
+type fakeAPIKeyRepository struct {
+	keys    []*models.APIKey
+	touched map[int64]int
+}
+
+func newFakeAPIKeyRepository() *fakeAPIKeyRepository {
+	return &fakeAPIKeyRepository{touched: make(map[int64]int)}
+}
+
+func (r *fakeAPIKeyRepository) Create(ctx context.Context, name, prefix string, salt, hash []byte, scopes []string) (*models.APIKey, error) {
+	key := &models.APIKey{ID: int64(len(r.keys) + 1), Name: name, Prefix: prefix, Salt: salt, Hash: hash, Scopes: scopes, CreatedAt: time.Now()}
+	r.keys = append(r.keys, key)
+	return key, nil
+}
+
+func (r *fakeAPIKeyRepository) GetByID(ctx context.Context, id int64) (*models.APIKey, error) {
+	for _, key := range r.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, repository.ErrAPIKeyNotFound
+}
+
+func (r *fakeAPIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+	return nil, repository.ErrAPIKeyNotFound
+}
+
+func (r *fakeAPIKeyRepository) List(ctx context.Context) ([]*models.APIKey, error) {
+	return r.keys, nil
+}
+
+func (r *fakeAPIKeyRepository) Rotate(ctx context.Context, id int64, prefix string, salt, hash []byte) (*models.APIKey, error) {
+	old, err := r.Revoke(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.Create(ctx, old.Name, prefix, salt, hash, old.Scopes)
+}
+
+func (r *fakeAPIKeyRepository) Revoke(ctx context.Context, id int64) (*models.APIKey, error) {
+	key, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !key.Active() {
+		return nil, repository.ErrAPIKeyRevoked
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return key, nil
+}
+
+func (r *fakeAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	r.touched[id]++
+	return nil
+}
+
+// holdsAll is a ScopeCheck for callers holding every scope
+func holdsAll(scope string) bool { return true }
+
+func TestAPIKeyService_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc := NewAPIKeyService(repo, logger.NewLogger())
+
+	created, err := svc.CreateAPIKey(ctx, &models.CreateAPIKeyRequest{Name: "nightly-sync", Scopes: []string{"users:read"}}, holdsAll)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if created.Key == "" || created.Prefix == "" {
+		t.Fatalf("CreateAPIKey() = %+v, expected a key and prefix", created)
+	}
+	if string(repo.keys[0].Hash) == created.Key {
+		t.Error("Expected the key to be stored hashed")
+	}
+
+	key, err := svc.Authenticate(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if key.ID != created.ID || repo.touched[key.ID] != 1 {
+		t.Errorf("Authenticate() = %+v, touched %d times", key, repo.touched[key.ID])
+	}
+
+	if _, err := svc.Authenticate(ctx, created.Key+"x"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with wrong secret error = %v, expected ErrInvalidAPIKey", err)
+	}
+	if _, err := svc.Authenticate(ctx, "not-a-key"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with malformed key error = %v, expected ErrInvalidAPIKey", err)
+	}
+
+	rotated, err := svc.RotateAPIKey(ctx, created.ID, holdsAll)
+	if err != nil {
+		t.Fatalf("RotateAPIKey() error = %v", err)
+	}
+	if rotated.Name != "nightly-sync" || len(rotated.Scopes) != 1 || rotated.Key == created.Key {
+		t.Errorf("RotateAPIKey() = %+v", rotated)
+	}
+	if _, err := svc.Authenticate(ctx, created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with rotated key error = %v, expected ErrInvalidAPIKey", err)
+	}
+	if _, err := svc.Authenticate(ctx, rotated.Key); err != nil {
+		t.Errorf("Authenticate() with replacement key error = %v", err)
+	}
+
+	if err := svc.RevokeAPIKey(ctx, rotated.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if err := svc.RevokeAPIKey(ctx, rotated.ID); !errors.Is(err, repository.ErrAPIKeyRevoked) {
+		t.Errorf("RevokeAPIKey() twice error = %v, expected ErrAPIKeyRevoked", err)
+	}
+	if _, err := svc.Authenticate(ctx, rotated.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with revoked key error = %v, expected ErrInvalidAPIKey", err)
+	}
+}
+
+func TestAPIKeyService_ScopeNotHeld(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc := NewAPIKeyService(repo, logger.NewLogger())
+	readOnly := func(scope string) bool { return scope == "users:read" }
+
+	req := &models.CreateAPIKeyRequest{Name: "escalate", Scopes: []string{"users:read", "users:delete"}}
+	if _, err := svc.CreateAPIKey(ctx, req, readOnly); !errors.Is(err, ErrScopeNotHeld) {
+		t.Errorf("CreateAPIKey() error = %v, expected ErrScopeNotHeld", err)
+	}
+	if len(repo.keys) != 0 {
+		t.Fatalf("CreateAPIKey() stored %d keys, expected none", len(repo.keys))
+	}
+
+	created, err := svc.CreateAPIKey(ctx, req, holdsAll)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if _, err := svc.RotateAPIKey(ctx, created.ID, readOnly); !errors.Is(err, ErrScopeNotHeld) {
+		t.Errorf("RotateAPIKey() error = %v, expected ErrScopeNotHeld", err)
+	}
+	if !repo.keys[0].Active() {
+		t.Error("Expected the key to stay active after a rejected rotation")
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"user-api/internal/models"
+	"user-api/internal/repository"
+)
+
+// fakeRepository is an in-memory UserRepository ordered by id. It ignores
+// filters other than deletion and age, and sort fields other than id.
+type fakeRepository struct {
+	users []*models.User
+}
+
+func newFakeRepository(names ...string) *fakeRepository {
+	repo := &fakeRepository{}
+	for _, name := range names {
+		repo.Create(context.Background(), name, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	return repo
+}
+
+func (r *fakeRepository) Create(ctx context.Context, name string, dob time.Time) (*models.User, error) {
+	user := &models.User{ID: int64(len(r.users) + 1), Name: name, DOB: dob}
+	r.users = append(r.users, user)
+	return user, nil
+}
+
+func (r *fakeRepository) BulkCreate(ctx context.Context, users []models.NewUser, bestEffort bool) ([]models.BulkCreateResult, error) {
+	results := make([]models.BulkCreateResult, len(users))
+	for i, u := range users {
+		user, _ := r.Create(ctx, u.Name, u.DOB)
+		results[i].ID = user.ID
+	}
+	return results, nil
+}
+
+func (r *fakeRepository) GetByID(ctx context.Context, id int64, includeDeleted bool) (*models.User, error) {
+	for _, user := range r.users {
+		if user.ID == id && (includeDeleted || user.DeletedAt == nil) {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *fakeRepository) Update(ctx context.Context, id int64, name string, dob time.Time, version *time.Time) (*models.User, error) {
+	return r.Patch(ctx, id, models.UserPatch{Name: &name, DOB: &dob}, version)
+}
+
+func (r *fakeRepository) Patch(ctx context.Context, id int64, patch models.UserPatch, version *time.Time) (*models.User, error) {
+	user, err := r.GetByID(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if version != nil && !user.UpdatedAt.Equal(*version) {
+		return nil, repository.ErrStaleVersion
+	}
+	user.UpdatedAt = user.UpdatedAt.Add(time.Microsecond)
+	if patch.Name != nil {
+		user.Name = *patch.Name
+	}
+	if patch.DOB != nil {
+		user.DOB = *patch.DOB
+	}
+	return user, nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, id int64, version *time.Time) error {
+	user, err := r.GetByID(ctx, id, false)
+	if err != nil {
+		return err
+	}
+	if version != nil && !user.UpdatedAt.Equal(*version) {
+		return repository.ErrStaleVersion
+	}
+	now := time.Now()
+	user.DeletedAt = &now
+	user.UpdatedAt = user.UpdatedAt.Add(time.Microsecond)
+	return nil
+}
+
+func (r *fakeRepository) Restore(ctx context.Context, id int64, version *time.Time) (*models.User, error) {
+	user, err := r.GetByID(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt == nil {
+		return nil, repository.ErrUserNotDeleted
+	}
+	if version != nil && !user.UpdatedAt.Equal(*version) {
+		return nil, repository.ErrStaleVersion
+	}
+	user.DeletedAt = nil
+	user.UpdatedAt = user.UpdatedAt.Add(time.Microsecond)
+	return user, nil
+}
+
+func (r *fakeRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var kept []*models.User
+	for _, user := range r.users {
+		if user.DeletedAt == nil || !user.DeletedAt.Before(deletedBefore) {
+			kept = append(kept, user)
+		}
+	}
+	purged := int64(len(r.users) - len(kept))
+	r.users = kept
+	return purged, nil
+}
+
+func (r *fakeRepository) List(ctx context.Context, opts models.UserListOptions) ([]*models.User, error) {
+	var users []*models.User
+	for _, user := range r.users {
+		if user.DeletedAt != nil && !opts.Filter.IncludeDeleted {
+			continue
+		}
+		age := models.CalculateAge(user.DOB, opts.Filter.AgeOn)
+		if (opts.Filter.MinAge != nil && age < *opts.Filter.MinAge) || (opts.Filter.MaxAge != nil && age > *opts.Filter.MaxAge) {
+			continue
+		}
+		if after := opts.After; after != nil {
+			if (!after.Backward && user.ID <= after.ID) || (after.Backward && user.ID >= after.ID) {
+				continue
+			}
+		}
+		users = append(users, user)
+	}
+
+	if opts.After != nil && opts.After.Backward {
+		sort.Slice(users, func(i, j int) bool { return users[i].ID > users[j].ID })
+	}
+
+	if opts.Offset > len(users) {
+		return nil, nil
+	}
+	users = users[opts.Offset:]
+	if len(users) > opts.Limit {
+		users = users[:opts.Limit]
+	}
+	return users, nil
+}
+
+func (r *fakeRepository) Stream(ctx context.Context, opts models.UserListOptions, fn func(*models.User) error) error {
+	if opts.Limit == 0 {
+		opts.Limit = len(r.users)
+	}
+	users, err := r.List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) Count(ctx context.Context, filter models.UserFilter) (int64, error) {
+	var count int64
+	err := r.Stream(ctx, models.UserListOptions{Filter: filter}, func(*models.User) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (r *fakeRepository) ListBirthdays(ctx context.Context, opts models.BirthdayListOptions) ([]models.UpcomingBirthday, error) {
+	var birthdays []models.UpcomingBirthday
+	for _, user := range r.users {
+		birthday := models.NextBirthday(user.DOB, opts.From)
+		if user.DeletedAt == nil && !birthday.After(opts.To) && birthday.After(user.DOB) {
+			birthdays = append(birthdays, models.UpcomingBirthday{User: user, Birthday: birthday})
+		}
+	}
+	sort.SliceStable(birthdays, func(i, j int) bool { return birthdays[i].Birthday.Before(birthdays[j].Birthday) })
+
+	if opts.Offset > len(birthdays) {
+		return nil, nil
+	}
+	birthdays = birthdays[opts.Offset:]
+	if opts.Limit > 0 && len(birthdays) > opts.Limit {
+		birthdays = birthdays[:opts.Limit]
+	}
+	return birthdays, nil
+}
+
+func (r *fakeRepository) CountBirthdays(ctx context.Context, from, to time.Time) (int64, error) {
+	birthdays, err := r.ListBirthdays(ctx, models.BirthdayListOptions{From: from, To: to})
+	return int64(len(birthdays)), err
+}
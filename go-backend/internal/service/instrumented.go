@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"user-api/internal/cursor"
+	"user-api/internal/metrics"
+	"user-api/internal/models"
+	"user-api/internal/repository"
+	"user-api/internal/tracing"
+)
+
+// instrumentedUserService traces the operations of a UserService and
+// counts them and their failures
+type instrumentedUserService struct {
+	next    UserService
+	metrics *metrics.Metrics
+}
+
+// NewInstrumentedUserService wraps next so every operation runs in its own
+// span and is counted in m, together with the reason when it fails. A nil m
+// only traces.
+func NewInstrumentedUserService(next UserService, m *metrics.Metrics) UserService {
+	return &instrumentedUserService{next: next, metrics: m}
+}
+
+// start begins a span for operation; the returned function ends it and
+// records the outcome
+func (s *instrumentedUserService) start(ctx context.Context, operation string) (context.Context, func(error)) {
+	ctx, span := tracing.Start(ctx, "UserService."+operation)
+	return ctx, func(err error) {
+		reason := errorReason(err)
+		if reason != "" {
+			span.SetAttributes(attribute.String("error.reason", reason))
+			// Client mistakes are expected outcomes, not span errors
+			if reason == "internal" {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+		}
+		span.End()
+
+		if s.metrics != nil {
+			s.metrics.ObserveOperation(operation, reason)
+		}
+	}
+}
+
+// errorReason classifies an operation error for the errors counter
+func errorReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, repository.ErrUserNotFound):
+		return "not_found"
+	case errors.Is(err, ErrPreconditionFailed), errors.Is(err, repository.ErrStaleVersion):
+		return "precondition_failed"
+	case errors.Is(err, repository.ErrUserNotDeleted):
+		return "conflict"
+	case errors.Is(err, ErrImportRejected), errors.Is(err, ErrPatchRejected):
+		return "rejected"
+	case errors.Is(err, ErrInvalidDOB), errors.Is(err, ErrInvalidFilter),
+		errors.Is(err, ErrInvalidTimezone), errors.Is(err, ErrInvalidAsOf),
+		errors.Is(err, models.ErrInvalidSort),
+		errors.Is(err, cursor.ErrInvalidCursor), errors.Is(err, repository.ErrInvalidKeyset):
+		return "invalid"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "internal"
+	}
+}
+
+func (s *instrumentedUserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
+	ctx, end := s.start(ctx, "create_user")
+	user, err := s.next.CreateUser(ctx, req)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) ImportUsers(ctx context.Context, rows []models.ImportRow, mode models.ImportMode) (*models.ImportReport, error) {
+	ctx, end := s.start(ctx, "import_users")
+	report, err := s.next.ImportUsers(ctx, rows, mode)
+	end(err)
+	return report, err
+}
+
+func (s *instrumentedUserService) GetUser(ctx context.Context, id int64, query *models.GetUserQuery) (*models.UserResponse, error) {
+	ctx, end := s.start(ctx, "get_user")
+	user, err := s.next.GetUser(ctx, id, query)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest, ifMatch string) (*models.UserResponse, error) {
+	ctx, end := s.start(ctx, "update_user")
+	user, err := s.next.UpdateUser(ctx, id, req, ifMatch)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) PatchUser(ctx context.Context, id int64, build PatchFunc, ifMatch string) (*models.UserResponse, error) {
+	ctx, end := s.start(ctx, "patch_user")
+	user, err := s.next.PatchUser(ctx, id, build, ifMatch)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) DeleteUser(ctx context.Context, id int64, ifMatch string) error {
+	ctx, end := s.start(ctx, "delete_user")
+	err := s.next.DeleteUser(ctx, id, ifMatch)
+	end(err)
+	return err
+}
+
+func (s *instrumentedUserService) RestoreUser(ctx context.Context, id int64, ifMatch string) (*models.UserResponse, error) {
+	ctx, end := s.start(ctx, "restore_user")
+	user, err := s.next.RestoreUser(ctx, id, ifMatch)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, end := s.start(ctx, "purge_deleted_users")
+	purged, err := s.next.PurgeDeletedUsers(ctx, deletedBefore)
+	end(err)
+	return purged, err
+}
+
+func (s *instrumentedUserService) ListUsers(ctx context.Context, query *models.ListUsersQuery) (*models.PaginatedResponse, error) {
+	ctx, end := s.start(ctx, "list_users")
+	page, err := s.next.ListUsers(ctx, query)
+	end(err)
+	return page, err
+}
+
+func (s *instrumentedUserService) ListUsersCursor(ctx context.Context, query *models.ListUsersQuery) (*models.CursorPaginatedResponse, error) {
+	ctx, end := s.start(ctx, "list_users_cursor")
+	page, err := s.next.ListUsersCursor(ctx, query)
+	end(err)
+	return page, err
+}
+
+func (s *instrumentedUserService) ListBirthdays(ctx context.Context, query *models.BirthdaysQuery) (*models.BirthdaysResponse, error) {
+	ctx, end := s.start(ctx, "list_birthdays")
+	page, err := s.next.ListBirthdays(ctx, query)
+	end(err)
+	return page, err
+}
+
+// ExportUsers ends its span and counts the export once it has finished
+// streaming, or when it fails before streaming starts. Queries made while
+// streaming are children of the span even though the stream runs after
+// the request handler returned.
+func (s *instrumentedUserService) ExportUsers(ctx context.Context, query *models.ListUsersQuery, includeAge bool) (ExportFunc, error) {
+	ctx, end := s.start(ctx, "export_users")
+	export, err := s.next.ExportUsers(ctx, query, includeAge)
+	if err != nil {
+		end(err)
+		return nil, err
+	}
+
+	span := trace.SpanFromContext(ctx)
+	return func(ctx context.Context, emit func(models.UserResponse) error) error {
+		err := export(trace.ContextWithSpan(ctx, span), emit)
+		end(err)
+		return err
+	}, nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-api/internal/metrics"
+	"user-api/internal/models"
+)
+
+func TestInstrumentedUserService(t *testing.T) {
+	m := metrics.New()
+	svc := NewInstrumentedUserService(newTestService(newFakeRepository("Alice")), m)
+	ctx := context.Background()
+
+	svc.GetUser(ctx, 1, &models.GetUserQuery{})
+	svc.GetUser(ctx, 99, &models.GetUserQuery{})
+	svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Bob", DOB: "not-a-date"})
+	// Bad query parameters are client mistakes, not internal errors
+	svc.GetUser(ctx, 1, &models.GetUserQuery{TZ: "Nowhere/Special"})
+	svc.GetUser(ctx, 1, &models.GetUserQuery{AsOf: "2021-02-30"})
+	svc.ListUsers(ctx, &models.ListUsersQuery{Page: 1, PageSize: 10, Sort: "shoe_size"})
+
+	export, err := svc.ExportUsers(ctx, &models.ListUsersQuery{}, false)
+	if err != nil {
+		t.Fatalf("ExportUsers() error = %v", err)
+	}
+	if err := export(ctx, func(models.UserResponse) error { return nil }); err != nil {
+		t.Fatalf("export() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`user_service_operations_total{operation="get_user"} 4`,
+		`user_service_errors_total{operation="get_user",reason="not_found"} 1`,
+		`user_service_errors_total{operation="get_user",reason="invalid"} 2`,
+		`user_service_errors_total{operation="list_users",reason="invalid"} 1`,
+		`user_service_errors_total{operation="create_user",reason="invalid"} 1`,
+		`user_service_operations_total{operation="export_users"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %s", want)
+		}
+	}
+	if strings.Contains(string(body), `reason="internal"`) {
+		t.Errorf("Expected no internal errors, got\n%s", body)
+	}
+}
@@ -2,157 +2,446 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.uber.org/zap"
 
+	"user-api/internal/clock"
+	"user-api/internal/cursor"
+	"user-api/internal/etag"
 	"user-api/internal/logger"
 	"user-api/internal/models"
 	"user-api/internal/repository"
 )
 
 var (
-	ErrInvalidDOB = errors.New("invalid date of birth format")
+	ErrInvalidDOB    = errors.New("invalid date of birth format")
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrInvalidTimezone is returned for a tz that is not an IANA time zone
+	ErrInvalidTimezone = errors.New("invalid time zone")
+	// ErrInvalidAsOf is returned for an as_of that is not a date
+	ErrInvalidAsOf = errors.New("invalid as_of date")
+	// ErrImportRejected is returned when an atomic import contains invalid rows
+	ErrImportRejected = errors.New("import rejected")
+	// ErrPreconditionFailed is returned when an If-Match precondition does
+	// not match the user's current version
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPatchRejected wraps the error of a PatchFunc that refused a user
+	ErrPatchRejected = errors.New("patch rejected")
 )
 
+// maxPatchAttempts bounds retries of a PATCH racing with concurrent writes
+const maxPatchAttempts = 3
+
 // UserService defines the interface for user business logic
 type UserService interface {
 	CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error)
-	GetUser(ctx context.Context, id int64) (*models.UserResponse, error)
-	UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest) (*models.UserResponse, error)
-	DeleteUser(ctx context.Context, id int64) error
-	ListUsers(ctx context.Context, page, pageSize int) (*models.PaginatedResponse, error)
+	ImportUsers(ctx context.Context, rows []models.ImportRow, mode models.ImportMode) (*models.ImportReport, error)
+	GetUser(ctx context.Context, id int64, query *models.GetUserQuery) (*models.UserResponse, error)
+	UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest, ifMatch string) (*models.UserResponse, error)
+	PatchUser(ctx context.Context, id int64, build PatchFunc, ifMatch string) (*models.UserResponse, error)
+	DeleteUser(ctx context.Context, id int64, ifMatch string) error
+	RestoreUser(ctx context.Context, id int64, ifMatch string) (*models.UserResponse, error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	ListUsers(ctx context.Context, query *models.ListUsersQuery) (*models.PaginatedResponse, error)
+	ListUsersCursor(ctx context.Context, query *models.ListUsersQuery) (*models.CursorPaginatedResponse, error)
+	ExportUsers(ctx context.Context, query *models.ListUsersQuery, includeAge bool) (ExportFunc, error)
+	ListBirthdays(ctx context.Context, query *models.BirthdaysQuery) (*models.BirthdaysResponse, error)
 }
 
+// PatchFunc computes a partial update from the user's current
+// representation. It is called again with the new representation whenever
+// a concurrent write forces a retry.
+type PatchFunc func(current models.UserResponse) (*models.PatchUserRequest, error)
+
+// ExportFunc streams exported users to emit, stopping at the first error
+type ExportFunc func(ctx context.Context, emit func(models.UserResponse) error) error
+
 type userService struct {
-	repo   repository.UserRepository
-	logger *logger.Logger
+	repo    repository.UserRepository
+	cursors *cursor.Codec
+	clock   clock.Clock
+	// location is the time zone ages are calculated in when a request does
+	// not name one
+	location *time.Location
+	logger   *logger.Logger
 }
 
 // NewUserService creates a new UserService instance
-func NewUserService(repo repository.UserRepository, logger *logger.Logger) UserService {
+func NewUserService(repo repository.UserRepository, cursors *cursor.Codec, clock clock.Clock, location *time.Location, logger *logger.Logger) UserService {
 	return &userService{
-		repo:   repo,
-		logger: logger,
+		repo:     repo,
+		cursors:  cursors,
+		clock:    clock,
+		location: location,
+		logger:   logger,
 	}
 }
 
+// log returns the request-scoped logger, which carries the request ID and
+// caller identity, falling back to the service logger
+func (s *userService) log(ctx context.Context) *logger.Logger {
+	return s.logger.FromContext(ctx)
+}
+
 // CreateUser creates a new user
 func (s *userService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
-	s.logger.Info("Creating new user", zap.String("name", req.Name))
+	s.log(ctx).Info("Creating new user", zap.String("name", req.Name))
 
 	dob, err := time.Parse("2006-01-02", req.DOB)
 	if err != nil {
-		s.logger.Error("Invalid DOB format", zap.Error(err))
+		s.log(ctx).Error("Invalid DOB format", zap.Error(err))
 		return nil, ErrInvalidDOB
 	}
 
 	user, err := s.repo.Create(ctx, req.Name, dob)
 	if err != nil {
-		s.logger.Error("Failed to create user", zap.Error(err))
+		s.log(ctx).Error("Failed to create user", zap.Error(err))
 		return nil, err
 	}
 
-	s.logger.Info("User created successfully", zap.Int64("user_id", user.ID))
+	s.log(ctx).Info("User created successfully", zap.Int64("user_id", user.ID))
 
-	response := user.ToResponse(false) // Don't include age in create response
+	response := user.ToResponse() // Don't include age in create response
 	return &response, nil
 }
 
+// ImportUsers creates users in bulk and reports the outcome of every row.
+// In atomic mode nothing is inserted unless every row is valid, in which
+// case the report is returned together with ErrImportRejected.
+func (s *userService) ImportUsers(ctx context.Context, rows []models.ImportRow, mode models.ImportMode) (*models.ImportReport, error) {
+	s.log(ctx).Info("Importing users", zap.Int("rows", len(rows)), zap.String("mode", string(mode)))
+
+	report := &models.ImportReport{
+		Mode:    mode,
+		Total:   len(rows),
+		Results: make([]models.ImportRowResult, len(rows)),
+	}
+
+	var users []models.NewUser
+	var positions []int
+	for i, row := range rows {
+		report.Results[i] = models.ImportRowResult{Row: row.Row, Errors: row.Errors}
+		if len(row.Errors) == 0 {
+			dob, err := time.Parse("2006-01-02", row.Request.DOB)
+			if err != nil {
+				report.Results[i].Errors = map[string]string{"dob": ErrInvalidDOB.Error()}
+			} else {
+				users = append(users, models.NewUser{Name: row.Request.Name, DOB: dob})
+				positions = append(positions, i)
+				continue
+			}
+		}
+		report.Results[i].Status = models.ImportStatusRejected
+		report.Rejected++
+	}
+
+	if mode == models.ImportModeAtomic && report.Rejected > 0 {
+		for _, i := range positions {
+			report.Results[i].Status = models.ImportStatusSkipped
+		}
+		s.log(ctx).Warn("Import rejected", zap.Int("rejected", report.Rejected))
+		return report, ErrImportRejected
+	}
+
+	results, err := s.repo.BulkCreate(ctx, users, mode == models.ImportModeBestEffort)
+	if err != nil {
+		s.log(ctx).Error("Failed to import users", zap.Error(err))
+		return nil, err
+	}
+
+	for j, result := range results {
+		i := positions[j]
+		if result.Err != nil {
+			s.log(ctx).Error("Failed to insert import row", zap.Int("row", rows[i].Row), zap.Error(result.Err))
+			report.Results[i].Status = models.ImportStatusRejected
+			report.Results[i].Errors = map[string]string{"row": "failed to insert user"}
+			report.Rejected++
+			continue
+		}
+		report.Results[i].Status = models.ImportStatusCreated
+		report.Results[i].ID = result.ID
+		report.Accepted++
+	}
+
+	s.log(ctx).Info("Users imported", zap.Int("accepted", report.Accepted), zap.Int("rejected", report.Rejected))
+	return report, nil
+}
+
 // GetUser retrieves a user by ID with calculated age
-func (s *userService) GetUser(ctx context.Context, id int64) (*models.UserResponse, error) {
-	s.logger.Debug("Fetching user", zap.Int64("user_id", id))
+func (s *userService) GetUser(ctx context.Context, id int64, query *models.GetUserQuery) (*models.UserResponse, error) {
+	s.log(ctx).Debug("Fetching user", zap.Int64("user_id", id))
+
+	today, err := s.ageDate(query.AsOf, query.TZ)
+	if err != nil {
+		return nil, err
+	}
 
-	user, err := s.repo.GetByID(ctx, id)
+	user, err := s.repo.GetByID(ctx, id, query.IncludeDeleted)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			s.logger.Warn("User not found", zap.Int64("user_id", id))
+			s.log(ctx).Warn("User not found", zap.Int64("user_id", id))
 			return nil, err
 		}
-		s.logger.Error("Failed to fetch user", zap.Error(err))
+		s.log(ctx).Error("Failed to fetch user", zap.Error(err))
 		return nil, err
 	}
 
-	response := user.ToResponse(true) // Include age in get response
+	response := user.ToResponseWithAge(today) // Include age in get response
 	return &response, nil
 }
 
 // UpdateUser updates an existing user
-func (s *userService) UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest) (*models.UserResponse, error) {
-	s.logger.Info("Updating user", zap.Int64("user_id", id))
+func (s *userService) UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest, ifMatch string) (*models.UserResponse, error) {
+	s.log(ctx).Info("Updating user", zap.Int64("user_id", id))
 
 	dob, err := time.Parse("2006-01-02", req.DOB)
 	if err != nil {
-		s.logger.Error("Invalid DOB format", zap.Error(err))
+		s.log(ctx).Error("Invalid DOB format", zap.Error(err))
 		return nil, ErrInvalidDOB
 	}
 
-	user, err := s.repo.Update(ctx, id, req.Name, dob)
+	version, err := s.checkPrecondition(ctx, id, ifMatch, false)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.Update(ctx, id, req.Name, dob, version)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			s.logger.Warn("User not found for update", zap.Int64("user_id", id))
+			s.log(ctx).Warn("User not found for update", zap.Int64("user_id", id))
 			return nil, err
 		}
-		s.logger.Error("Failed to update user", zap.Error(err))
+		if errors.Is(err, repository.ErrStaleVersion) {
+			s.log(ctx).Warn("User modified concurrently", zap.Int64("user_id", id))
+			return nil, ErrPreconditionFailed
+		}
+		s.log(ctx).Error("Failed to update user", zap.Error(err))
 		return nil, err
 	}
 
-	s.logger.Info("User updated successfully", zap.Int64("user_id", user.ID))
+	s.log(ctx).Info("User updated successfully", zap.Int64("user_id", user.ID))
 
-	response := user.ToResponse(false) // Don't include age in update response
+	response := user.ToResponse() // Don't include age in update response
 	return &response, nil
 }
 
-// DeleteUser removes a user
-func (s *userService) DeleteUser(ctx context.Context, id int64) error {
-	s.logger.Info("Deleting user", zap.Int64("user_id", id))
+// PatchUser partially updates an existing user with the request build
+// computes from it. The request is always written against the version it
+// was computed from; without an If-Match, a concurrent write is retried.
+func (s *userService) PatchUser(ctx context.Context, id int64, build PatchFunc, ifMatch string) (*models.UserResponse, error) {
+	s.log(ctx).Info("Patching user", zap.Int64("user_id", id))
 
-	err := s.repo.Delete(ctx, id)
+	for attempt := 1; ; attempt++ {
+		current, err := s.repo.GetByID(ctx, id, false)
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				s.log(ctx).Warn("User not found for patch", zap.Int64("user_id", id))
+				return nil, err
+			}
+			s.log(ctx).Error("Failed to fetch user", zap.Error(err))
+			return nil, err
+		}
+
+		if ifMatch != "" && !etag.MatchVersion(ifMatch, current.ETag()) {
+			s.log(ctx).Warn("If-Match precondition failed", zap.Int64("user_id", id))
+			return nil, ErrPreconditionFailed
+		}
+
+		req, err := build(current.ToResponse())
+		if err != nil {
+			s.log(ctx).Warn("Patch rejected", zap.Int64("user_id", id), zap.Error(err))
+			return nil, fmt.Errorf("%w: %w", ErrPatchRejected, err)
+		}
+
+		patch := models.UserPatch{Name: req.Name}
+		if req.DOB != nil {
+			dob, err := time.Parse("2006-01-02", *req.DOB)
+			if err != nil {
+				s.log(ctx).Error("Invalid DOB format", zap.Error(err))
+				return nil, ErrInvalidDOB
+			}
+			patch.DOB = &dob
+		}
+
+		user, err := s.repo.Patch(ctx, id, patch, &current.UpdatedAt)
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				s.log(ctx).Warn("User not found for patch", zap.Int64("user_id", id))
+				return nil, err
+			}
+			if errors.Is(err, repository.ErrStaleVersion) {
+				if ifMatch == "" && attempt < maxPatchAttempts {
+					s.log(ctx).Info("User modified concurrently, retrying patch", zap.Int64("user_id", id), zap.Int("attempt", attempt))
+					continue
+				}
+				s.log(ctx).Warn("User modified concurrently", zap.Int64("user_id", id))
+				return nil, ErrPreconditionFailed
+			}
+			s.log(ctx).Error("Failed to patch user", zap.Error(err))
+			return nil, err
+		}
+
+		s.log(ctx).Info("User patched successfully", zap.Int64("user_id", user.ID))
+
+		response := user.ToResponse() // Don't include age in update response
+		return &response, nil
+	}
+}
+
+// DeleteUser soft deletes a user
+func (s *userService) DeleteUser(ctx context.Context, id int64, ifMatch string) error {
+	s.log(ctx).Info("Deleting user", zap.Int64("user_id", id))
+
+	version, err := s.checkPrecondition(ctx, id, ifMatch, false)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Delete(ctx, id, version)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			s.logger.Warn("User not found for deletion", zap.Int64("user_id", id))
+			s.log(ctx).Warn("User not found for deletion", zap.Int64("user_id", id))
 			return err
 		}
-		s.logger.Error("Failed to delete user", zap.Error(err))
+		if errors.Is(err, repository.ErrStaleVersion) {
+			s.log(ctx).Warn("User modified concurrently", zap.Int64("user_id", id))
+			return ErrPreconditionFailed
+		}
+		s.log(ctx).Error("Failed to delete user", zap.Error(err))
 		return err
 	}
 
-	s.logger.Info("User deleted successfully", zap.Int64("user_id", id))
+	s.log(ctx).Info("User deleted successfully", zap.Int64("user_id", id))
 	return nil
 }
 
-// ListUsers retrieves paginated list of users
-func (s *userService) ListUsers(ctx context.Context, page, pageSize int) (*models.PaginatedResponse, error) {
-	s.logger.Debug("Listing users", zap.Int("page", page), zap.Int("page_size", pageSize))
+// RestoreUser undoes the soft deletion of a user
+func (s *userService) RestoreUser(ctx context.Context, id int64, ifMatch string) (*models.UserResponse, error) {
+	s.log(ctx).Info("Restoring user", zap.Int64("user_id", id))
+
+	// The user is deleted, so its ETag is the one a deleted read returned
+	version, err := s.checkPrecondition(ctx, id, ifMatch, true)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.Restore(ctx, id, version)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) || errors.Is(err, repository.ErrUserNotDeleted) {
+			s.log(ctx).Warn("User cannot be restored", zap.Int64("user_id", id), zap.Error(err))
+			return nil, err
+		}
+		if errors.Is(err, repository.ErrStaleVersion) {
+			s.log(ctx).Warn("User modified concurrently", zap.Int64("user_id", id))
+			return nil, ErrPreconditionFailed
+		}
+		s.log(ctx).Error("Failed to restore user", zap.Error(err))
+		return nil, err
+	}
+
+	s.log(ctx).Info("User restored successfully", zap.Int64("user_id", id))
+
+	// The default time zone always loads
+	today, _ := s.ageDate("", "")
+	response := user.ToResponseWithAge(today)
+	return &response, nil
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted before the given time
+func (s *userService) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	purged, err := s.repo.PurgeDeleted(ctx, deletedBefore)
+	if err != nil {
+		s.log(ctx).Error("Failed to purge deleted users", zap.Error(err))
+		return 0, err
+	}
+
+	if purged > 0 {
+		s.log(ctx).Info("Purged deleted users", zap.Int64("count", purged), zap.Time("deleted_before", deletedBefore))
+	}
+	return purged, nil
+}
+
+// checkPrecondition evaluates an If-Match header against the user's current
+// ETag. It returns the version the following write must be conditioned on,
+// or nil when the request is unconditional. Soft-deleted users are only
+// found with includeDeleted.
+func (s *userService) checkPrecondition(ctx context.Context, id int64, ifMatch string, includeDeleted bool) (*time.Time, error) {
+	if ifMatch == "" {
+		return nil, nil
+	}
+
+	user, err := s.repo.GetByID(ctx, id, includeDeleted)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			s.log(ctx).Warn("User not found", zap.Int64("user_id", id))
+			return nil, err
+		}
+		s.log(ctx).Error("Failed to fetch user", zap.Error(err))
+		return nil, err
+	}
+
+	if !etag.MatchVersion(ifMatch, user.ETag()) {
+		s.log(ctx).Warn("If-Match precondition failed", zap.Int64("user_id", id))
+		return nil, ErrPreconditionFailed
+	}
+
+	return &user.UpdatedAt, nil
+}
+
+// ListUsers retrieves a filtered, paginated list of users
+func (s *userService) ListUsers(ctx context.Context, query *models.ListUsersQuery) (*models.PaginatedResponse, error) {
+	page, pageSize := query.Page, query.PageSize
+	s.log(ctx).Debug("Listing users", zap.Int("page", page), zap.Int("page_size", pageSize))
+
+	today, err := s.ageDate(query.AsOf, query.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseUserFilter(query, today)
+	if err != nil {
+		s.log(ctx).Warn("Invalid list filter", zap.Error(err))
+		return nil, err
+	}
+
+	sort, err := models.ParseSort(query.Sort)
+	if err != nil {
+		s.log(ctx).Warn("Invalid list sort", zap.Error(err))
+		return nil, err
+	}
 
 	// Calculate offset
 	offset := (page - 1) * pageSize
 
 	// Get users
-	users, err := s.repo.List(ctx, pageSize, offset)
+	users, err := s.repo.List(ctx, models.UserListOptions{
+		Filter: filter,
+		Sort:   sort,
+		Limit:  pageSize,
+		Offset: offset,
+	})
 	if err != nil {
-		s.logger.Error("Failed to list users", zap.Error(err))
+		s.log(ctx).Error("Failed to list users", zap.Error(err))
 		return nil, err
 	}
 
-	// Get total count
-	totalCount, err := s.repo.Count(ctx)
+	// Get total count honouring the same filter
+	totalCount, err := s.repo.Count(ctx, filter)
 	if err != nil {
-		s.logger.Error("Failed to count users", zap.Error(err))
+		s.log(ctx).Error("Failed to count users", zap.Error(err))
 		return nil, err
 	}
 
 	// Convert to response with age
-	var responses []models.UserResponse
+	responses := make([]models.UserResponse, 0, len(users))
 	for _, user := range users {
-		responses = append(responses, user.ToResponse(true)) // Include age in list response
-	}
-
-	// Calculate total pages
-	totalPages := int(totalCount) / pageSize
-	if int(totalCount)%pageSize > 0 {
-		totalPages++
+		responses = append(responses, user.ToResponseWithAge(today)) // Include age in list response
 	}
 
 	return &models.PaginatedResponse{
@@ -160,6 +449,386 @@ func (s *userService) ListUsers(ctx context.Context, page, pageSize int) (*model
 		Page:       page,
 		PageSize:   pageSize,
 		TotalCount: totalCount,
-		TotalPages: totalPages,
+		TotalPages: pageCount(totalCount, pageSize),
+		Sort:       models.FormatSort(sort),
+	}, nil
+}
+
+// ListUsersCursor retrieves a filtered list of users using keyset pagination.
+// Unlike ListUsers it never counts the table, so its cost does not grow with
+// the page position.
+func (s *userService) ListUsersCursor(ctx context.Context, query *models.ListUsersQuery) (*models.CursorPaginatedResponse, error) {
+	s.log(ctx).Debug("Listing users by cursor", zap.Int("limit", query.Limit))
+
+	today, err := s.ageDate(query.AsOf, query.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseUserFilter(query, today)
+	if err != nil {
+		s.log(ctx).Warn("Invalid list filter", zap.Error(err))
+		return nil, err
+	}
+
+	sort, err := models.ParseSort(query.Sort)
+	if err != nil {
+		s.log(ctx).Warn("Invalid list sort", zap.Error(err))
+		return nil, err
+	}
+
+	scope := cursorScope(filter, sort, query.AsOf)
+	fields := keysetFields(sort)
+
+	// Fetch one extra row to find out whether another page follows
+	opts := models.UserListOptions{
+		Filter: filter,
+		Sort:   sort,
+		Limit:  query.Limit + 1,
+	}
+
+	var cur *cursor.Cursor
+	if query.Cursor != "" {
+		cur, err = s.cursors.Decode(query.Cursor)
+		if err != nil {
+			s.log(ctx).Warn("Rejected cursor", zap.Error(err))
+			return nil, err
+		}
+		opts.After, err = cursorKeyset(cur, scope, fields)
+		if err != nil {
+			s.log(ctx).Warn("Rejected cursor", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	users, err := s.repo.List(ctx, opts)
+	if err != nil {
+		s.log(ctx).Error("Failed to list users", zap.Error(err))
+		return nil, err
+	}
+
+	hasMore := len(users) > query.Limit
+	if hasMore {
+		users = users[:query.Limit]
+	}
+
+	// Backward pages are fetched in reverse order
+	backward := cur != nil && cur.Backward
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	responses := make([]models.UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, user.ToResponseWithAge(today))
+	}
+
+	result := &models.CursorPaginatedResponse{
+		Data:  responses,
+		Limit: query.Limit,
+		Sort:  models.FormatSort(sort),
+	}
+
+	if len(users) == 0 {
+		return result, nil
+	}
+
+	hasNext, hasPrev := hasMore, cur != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if hasNext {
+		result.NextCursor, err = s.encodeCursor(users[len(users)-1], scope, fields, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		result.PrevCursor, err = s.encodeCursor(users[0], scope, fields, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// ExportUsers validates an export request and returns a function streaming
+// every matching user. Validation errors are reported up front so they can
+// still be turned into an error response before streaming starts.
+func (s *userService) ExportUsers(ctx context.Context, query *models.ListUsersQuery, includeAge bool) (ExportFunc, error) {
+	today, err := s.ageDate(query.AsOf, query.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseUserFilter(query, today)
+	if err != nil {
+		s.log(ctx).Warn("Invalid export filter", zap.Error(err))
+		return nil, err
+	}
+
+	sort, err := models.ParseSort(query.Sort)
+	if err != nil {
+		s.log(ctx).Warn("Invalid export sort", zap.Error(err))
+		return nil, err
+	}
+
+	return func(ctx context.Context, emit func(models.UserResponse) error) error {
+		s.log(ctx).Info("Exporting users")
+
+		count := 0
+		err := s.repo.Stream(ctx, models.UserListOptions{Filter: filter, Sort: sort}, func(user *models.User) error {
+			count++
+			if includeAge {
+				return emit(user.ToResponseWithAge(today))
+			}
+			return emit(user.ToResponse())
+		})
+		if err != nil {
+			s.log(ctx).Error("Failed to export users", zap.Int("exported", count), zap.Error(err))
+			return err
+		}
+
+		s.log(ctx).Info("Users exported", zap.Int("exported", count))
+		return nil
 	}, nil
 }
+
+// ListBirthdays lists users whose next birthday falls within a range of
+// dates, soonest first, with the age they turn
+func (s *userService) ListBirthdays(ctx context.Context, query *models.BirthdaysQuery) (*models.BirthdaysResponse, error) {
+	from, to, err := s.birthdayRange(query)
+	if err != nil {
+		s.log(ctx).Warn("Invalid birthday range", zap.Error(err))
+		return nil, err
+	}
+
+	page, pageSize := query.Page, query.PageSize
+	s.log(ctx).Debug("Listing birthdays",
+		zap.String("from", from.Format("2006-01-02")),
+		zap.String("to", to.Format("2006-01-02")),
+		zap.Int("page", page),
+	)
+
+	birthdays, err := s.repo.ListBirthdays(ctx, models.BirthdayListOptions{
+		From:   from,
+		To:     to,
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to list birthdays", zap.Error(err))
+		return nil, err
+	}
+
+	totalCount, err := s.repo.CountBirthdays(ctx, from, to)
+	if err != nil {
+		s.log(ctx).Error("Failed to count birthdays", zap.Error(err))
+		return nil, err
+	}
+
+	responses := make([]models.BirthdayResponse, 0, len(birthdays))
+	for _, birthday := range birthdays {
+		responses = append(responses, birthday.ToResponse())
+	}
+
+	return &models.BirthdaysResponse{
+		PaginatedResponse: models.PaginatedResponse{
+			Data:       responses,
+			Page:       page,
+			PageSize:   pageSize,
+			TotalCount: totalCount,
+			TotalPages: pageCount(totalCount, pageSize),
+		},
+		From: from.Format("2006-01-02"),
+		To:   to.Format("2006-01-02"),
+	}, nil
+}
+
+// birthdayRange resolves the inclusive range of dates a birthday search
+// covers: from and to when given, otherwise within_days from today
+func (s *userService) birthdayRange(query *models.BirthdaysQuery) (time.Time, time.Time, error) {
+	if query.From == "" && query.To == "" {
+		days := models.DefaultBirthdayWindow
+		if query.WithinDays != nil {
+			days = *query.WithinDays
+		}
+		today, err := s.ageDate("", query.TZ)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return today, today.AddDate(0, 0, days), nil
+	}
+
+	if query.WithinDays != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: within_days cannot be combined with from and to", ErrInvalidFilter)
+	}
+	if query.From == "" || query.To == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from and to must be given together", ErrInvalidFilter)
+	}
+	from, err := time.Parse("2006-01-02", query.From)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be in format 2006-01-02", ErrInvalidFilter)
+	}
+	to, err := time.Parse("2006-01-02", query.To)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be in format 2006-01-02", ErrInvalidFilter)
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must not be after to", ErrInvalidFilter)
+	}
+	if to.After(from.AddDate(0, 0, models.MaxBirthdayWindow)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be at most %d days after from", ErrInvalidFilter, models.MaxBirthdayWindow)
+	}
+	return from, to, nil
+}
+
+// pageCount returns the number of pages needed for count items
+func pageCount(count int64, pageSize int) int {
+	pages := int(count) / pageSize
+	if int(count)%pageSize > 0 {
+		pages++
+	}
+	return pages
+}
+
+// ageDate returns the date ages are calculated on, as midnight UTC like
+// stored DOBs: asOf when given, otherwise the current date in the time zone
+// named by tz, or in the default time zone when tz is empty
+func (s *userService) ageDate(asOf, tz string) (time.Time, error) {
+	location := s.location
+	if tz != "" {
+		var err error
+		location, err = loadLocation(tz)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if asOf != "" {
+		date, err := time.Parse("2006-01-02", asOf)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: as_of must be in format 2006-01-02", ErrInvalidAsOf)
+		}
+		return date, nil
+	}
+	year, month, day := s.clock.Now().In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// loadLocation loads an IANA time zone. Local is rejected as it depends on
+// the server.
+func loadLocation(tz string) (*time.Location, error) {
+	location, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, fmt.Errorf("%w: tz must be an IANA time zone such as Europe/Berlin", ErrInvalidTimezone)
+	}
+	return location, nil
+}
+
+// encodeCursor builds a signed cursor pointing at the given boundary row
+func (s *userService) encodeCursor(user *models.User, scope string, fields []string, backward bool) (string, error) {
+	cur := cursor.Cursor{
+		Scope:    scope,
+		ID:       user.ID,
+		Backward: backward,
+	}
+	for _, field := range fields {
+		cur.Values = append(cur.Values, user.SortValue(field))
+	}
+
+	token, err := s.cursors.Encode(cur)
+	if err != nil {
+		s.logger.Error("Failed to encode cursor", zap.Error(err))
+		return "", err
+	}
+	return token, nil
+}
+
+// cursorKeyset converts a decoded cursor into a repository keyset, checking
+// that it was issued for the same sort and filters
+func cursorKeyset(cur *cursor.Cursor, scope string, fields []string) (*models.Keyset, error) {
+	if cur.Scope != scope {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort or filter", cursor.ErrInvalidCursor)
+	}
+	if len(cur.Values) != len(fields) {
+		return nil, cursor.ErrInvalidCursor
+	}
+
+	keyset := &models.Keyset{ID: cur.ID, Backward: cur.Backward}
+	for i, field := range fields {
+		value, err := models.ParseSortValue(field, cur.Values[i])
+		if err != nil {
+			return nil, cursor.ErrInvalidCursor
+		}
+		keyset.Values = append(keyset.Values, value)
+	}
+
+	return keyset, nil
+}
+
+// cursorScope fingerprints the sort, filters and as_of date a cursor is
+// valid for
+func cursorScope(filter models.UserFilter, sort []models.SortField, asOf string) string {
+	raw, _ := json.Marshal(struct {
+		Filter models.UserFilter
+		Sort   string
+		AsOf   string `json:",omitempty"`
+	}{filter, models.FormatSort(sort), asOf})
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
+
+// keysetFields returns the sort fields preceding the id tie-breaker
+func keysetFields(sort []models.SortField) []string {
+	var fields []string
+	for _, f := range sort {
+		if f.Field == "id" {
+			break
+		}
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+// parseUserFilter converts raw list query parameters into a UserFilter
+// whose age bounds apply on today
+func parseUserFilter(query *models.ListUsersQuery, today time.Time) (models.UserFilter, error) {
+	filter := models.UserFilter{
+		NameContains: query.Name,
+		NamePrefix:   query.NamePrefix,
+		MinAge:       query.MinAge,
+		MaxAge:       query.MaxAge,
+		AgeOn:        today,
+
+		IncludeDeleted: query.IncludeDeleted,
+	}
+
+	if query.DOBFrom != "" {
+		dob, err := time.Parse("2006-01-02", query.DOBFrom)
+		if err != nil {
+			return filter, fmt.Errorf("%w: dob_from must be in format 2006-01-02", ErrInvalidFilter)
+		}
+		filter.DOBFrom = &dob
+	}
+	if query.DOBTo != "" {
+		dob, err := time.Parse("2006-01-02", query.DOBTo)
+		if err != nil {
+			return filter, fmt.Errorf("%w: dob_to must be in format 2006-01-02", ErrInvalidFilter)
+		}
+		filter.DOBTo = &dob
+	}
+
+	if filter.DOBFrom != nil && filter.DOBTo != nil && filter.DOBFrom.After(*filter.DOBTo) {
+		return filter, fmt.Errorf("%w: dob_from must not be after dob_to", ErrInvalidFilter)
+	}
+	if filter.MinAge != nil && filter.MaxAge != nil && *filter.MinAge > *filter.MaxAge {
+		return filter, fmt.Errorf("%w: min_age must not be greater than max_age", ErrInvalidFilter)
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"user-api/internal/models"
+)
+
+func TestNextBirthday(t *testing.T) {
+	tests := []struct {
+		name     string
+		dob      string
+		from     string
+		expected string
+	}{
+		{"later this year", "1990-07-01", "2024-06-15", "2024-07-01"},
+		{"today", "1990-06-15", "2024-06-15", "2024-06-15"},
+		{"passed this year", "1990-06-14", "2024-06-15", "2025-06-14"},
+		{"wraps past December", "1990-01-03", "2024-12-20", "2025-01-03"},
+		{"leapling in leap year", "2000-02-29", "2024-02-01", "2024-02-29"},
+		{"leapling in common year", "2000-02-29", "2023-02-01", "2023-02-28"},
+		{"leapling wrapping into common year", "2000-02-29", "2024-03-01", "2025-02-28"},
+		{"leapling wrapping into leap year", "2000-02-29", "2023-03-01", "2024-02-29"},
+		{"born on from", "2024-06-15", "2024-06-15", "2025-06-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dob, _ := time.Parse("2006-01-02", tt.dob)
+			from, _ := time.Parse("2006-01-02", tt.from)
+			if got := models.NextBirthday(dob, from).Format("2006-01-02"); got != tt.expected {
+				t.Errorf("NextBirthday(%s, %s) = %s, expected %s", tt.dob, tt.from, got, tt.expected)
+			}
+		})
+	}
+}
+
+func newBirthdayRepository() *fakeRepository {
+	repo := newFakeRepository()
+	for _, u := range []struct {
+		name string
+		dob  time.Time
+	}{
+		{"Newyear", date(1990, 1, 1)},
+		{"Leapling", date(2000, 2, 29)},
+		{"Midsummer", date(1985, 6, 20)},
+		{"Today", date(1994, 6, 15)},
+		{"Yesterday", date(1994, 6, 14)},
+		{"Newborn", date(2024, 6, 15)},
+		{"Eve", date(1970, 12, 31)},
+	} {
+		repo.Create(context.Background(), u.name, u.dob)
+	}
+	return repo
+}
+
+func birthdayNames(t *testing.T, page *models.BirthdaysResponse) []string {
+	t.Helper()
+	var names []string
+	for _, b := range page.Data.([]models.BirthdayResponse) {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+func TestListBirthdays_WithinDays(t *testing.T) {
+	svc := newTestService(newBirthdayRepository())
+
+	// testNow is 2024-06-15; the default window is 30 days
+	page, err := svc.ListBirthdays(context.Background(), &models.BirthdaysQuery{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListBirthdays() error = %v", err)
+	}
+	if page.From != "2024-06-15" || page.To != "2024-07-15" {
+		t.Errorf("range = %s to %s, expected 2024-06-15 to 2024-07-15", page.From, page.To)
+	}
+	birthdays := page.Data.([]models.BirthdayResponse)
+	if len(birthdays) != 2 || page.TotalCount != 2 {
+		t.Fatalf("ListBirthdays() = %+v, expected Today and Midsummer", birthdays)
+	}
+	if b := birthdays[0]; b.Name != "Today" || b.Birthday != "2024-06-15" || b.Turns != 30 {
+		t.Errorf("first birthday = %+v, expected Today turning 30 on 2024-06-15", b)
+	}
+	if b := birthdays[1]; b.Name != "Midsummer" || b.Birthday != "2024-06-20" || b.Turns != 39 {
+		t.Errorf("second birthday = %+v, expected Midsummer turning 39 on 2024-06-20", b)
+	}
+
+	// A year ahead everyone is listed once, at their next birthday
+	days := 365
+	page, err = svc.ListBirthdays(context.Background(), &models.BirthdaysQuery{Page: 1, PageSize: 10, WithinDays: &days})
+	if err != nil {
+		t.Fatalf("ListBirthdays() error = %v", err)
+	}
+	expected := []string{"Today", "Midsummer", "Eve", "Newyear", "Leapling", "Yesterday", "Newborn"}
+	if names := birthdayNames(t, page); len(names) != len(expected) {
+		t.Errorf("ListBirthdays(365 days) = %v, expected %v", names, expected)
+	} else {
+		for i := range expected {
+			if names[i] != expected[i] {
+				t.Errorf("ListBirthdays(365 days) = %v, expected %v", names, expected)
+				break
+			}
+		}
+	}
+}
+
+func TestListBirthdays_Range(t *testing.T) {
+	svc := newTestService(newBirthdayRepository())
+
+	// Ranges wrap past December, and February 29 birthdays fall on
+	// February 28 in common years
+	page, err := svc.ListBirthdays(context.Background(), &models.BirthdaysQuery{Page: 1, PageSize: 10, From: "2024-12-31", To: "2025-02-28"})
+	if err != nil {
+		t.Fatalf("ListBirthdays() error = %v", err)
+	}
+	birthdays := page.Data.([]models.BirthdayResponse)
+	expected := []models.BirthdayResponse{
+		{ID: 7, Name: "Eve", DOB: "1970-12-31", Birthday: "2024-12-31", Turns: 54},
+		{ID: 1, Name: "Newyear", DOB: "1990-01-01", Birthday: "2025-01-01", Turns: 35},
+		{ID: 2, Name: "Leapling", DOB: "2000-02-29", Birthday: "2025-02-28", Turns: 25},
+	}
+	if len(birthdays) != len(expected) {
+		t.Fatalf("ListBirthdays() = %+v, expected %+v", birthdays, expected)
+	}
+	for i := range expected {
+		if birthdays[i] != expected[i] {
+			t.Errorf("birthday %d = %+v, expected %+v", i, birthdays[i], expected[i])
+		}
+	}
+
+	// Pages split the same ordering
+	page, err = svc.ListBirthdays(context.Background(), &models.BirthdaysQuery{Page: 2, PageSize: 2, From: "2024-12-31", To: "2025-02-28"})
+	if err != nil {
+		t.Fatalf("ListBirthdays() error = %v", err)
+	}
+	if names := birthdayNames(t, page); len(names) != 1 || names[0] != "Leapling" || page.TotalPages != 2 {
+		t.Errorf("page 2 = %v of %d pages, expected [Leapling] of 2", names, page.TotalPages)
+	}
+}
+
+func TestListBirthdays_InvalidRange(t *testing.T) {
+	svc := newTestService(newBirthdayRepository())
+	days := 7
+
+	tests := []struct {
+		name     string
+		query    models.BirthdaysQuery
+		expected error
+	}{
+		{"within_days with range", models.BirthdaysQuery{WithinDays: &days, From: "2024-01-01", To: "2024-01-31"}, ErrInvalidFilter},
+		{"from without to", models.BirthdaysQuery{From: "2024-01-01"}, ErrInvalidFilter},
+		{"from after to", models.BirthdaysQuery{From: "2024-02-01", To: "2024-01-31"}, ErrInvalidFilter},
+		{"longer than a year", models.BirthdaysQuery{From: "2024-01-01", To: "2025-01-01"}, ErrInvalidFilter},
+		{"unknown time zone", models.BirthdaysQuery{TZ: "Nowhere/Special"}, ErrInvalidTimezone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Page, tt.query.PageSize = 1, 10
+			if _, err := svc.ListBirthdays(context.Background(), &tt.query); !errors.Is(err, tt.expected) {
+				t.Errorf("ListBirthdays() error = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"user-api/internal/models"
+)
+
+func TestExportUsers_StreamsAllUsers(t *testing.T) {
+	repo := newFakeRepository("Alice", "Bob", "Carol")
+	now := time.Now()
+	repo.users[1].DeletedAt = &now
+	svc := newTestService(repo)
+
+	stream, err := svc.ExportUsers(context.Background(), &models.ListUsersQuery{}, true)
+	if err != nil {
+		t.Fatalf("ExportUsers() error = %v", err)
+	}
+
+	var names []string
+	err = stream(context.Background(), func(user models.UserResponse) error {
+		if user.Age != 34 {
+			t.Errorf("Expected age 34 for %s, got %d", user.Name, user.Age)
+		}
+		names = append(names, user.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream() error = %v", err)
+	}
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Carol" {
+		t.Errorf("Exported %v, expected [Alice Carol]", names)
+	}
+}
+
+func TestExportUsers_StopsOnEmitError(t *testing.T) {
+	svc := newTestService(newFakeRepository("Alice", "Bob", "Carol"))
+
+	stream, err := svc.ExportUsers(context.Background(), &models.ListUsersQuery{}, false)
+	if err != nil {
+		t.Fatalf("ExportUsers() error = %v", err)
+	}
+
+	errClosed := errors.New("client went away")
+	emitted := 0
+	err = stream(context.Background(), func(models.UserResponse) error {
+		emitted++
+		return errClosed
+	})
+	if !errors.Is(err, errClosed) {
+		t.Errorf("stream() error = %v, expected %v", err, errClosed)
+	}
+	if emitted != 1 {
+		t.Errorf("Expected export to stop after 1 user, emitted %d", emitted)
+	}
+}
+
+func TestExportUsers_InvalidSort(t *testing.T) {
+	svc := newTestService(newFakeRepository())
+
+	if _, err := svc.ExportUsers(context.Background(), &models.ListUsersQuery{Sort: "password"}, false); !errors.Is(err, models.ErrInvalidSort) {
+		t.Errorf("ExportUsers() error = %v, expected ErrInvalidSort", err)
+	}
+}
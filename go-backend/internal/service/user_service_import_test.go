@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"user-api/internal/models"
+)
+
+func importRows() []models.ImportRow {
+	return []models.ImportRow{
+		{Row: 1, Request: models.CreateUserRequest{Name: "Alice", DOB: "1990-05-10"}},
+		{Row: 2, Request: models.CreateUserRequest{Name: "", DOB: "1990-05-10"}, Errors: map[string]string{"Name": "Name is required"}},
+		{Row: 3, Request: models.CreateUserRequest{Name: "Carol", DOB: "2001-12-31"}},
+	}
+}
+
+func TestImportUsers_Atomic(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	report, err := svc.ImportUsers(context.Background(), importRows(), models.ImportModeAtomic)
+	if !errors.Is(err, ErrImportRejected) {
+		t.Fatalf("ImportUsers() error = %v, expected ErrImportRejected", err)
+	}
+	if len(repo.users) != 0 {
+		t.Errorf("Expected no users to be inserted, got %d", len(repo.users))
+	}
+	if report.Accepted != 0 || report.Rejected != 1 {
+		t.Errorf("Expected 0 accepted and 1 rejected, got %d and %d", report.Accepted, report.Rejected)
+	}
+	if report.Results[0].Status != models.ImportStatusSkipped || report.Results[1].Status != models.ImportStatusRejected {
+		t.Errorf("Unexpected statuses: %+v", report.Results)
+	}
+}
+
+func TestImportUsers_BestEffort(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	report, err := svc.ImportUsers(context.Background(), importRows(), models.ImportModeBestEffort)
+	if err != nil {
+		t.Fatalf("ImportUsers() error = %v", err)
+	}
+	if report.Accepted != 2 || report.Rejected != 1 || len(repo.users) != 2 {
+		t.Errorf("Expected 2 accepted and 1 rejected, got %+v", report)
+	}
+	if report.Results[2].Status != models.ImportStatusCreated || report.Results[2].ID != 2 {
+		t.Errorf("Expected row 3 to be created with id 2, got %+v", report.Results[2])
+	}
+}
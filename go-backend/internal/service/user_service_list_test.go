@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"user-api/internal/clock"
+	"user-api/internal/cursor"
+	"user-api/internal/logger"
+	"user-api/internal/models"
+)
+
+// testNow is the time reported to services built by newTestService
+var testNow = time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestService(repo *fakeRepository) UserService {
+	return NewUserService(repo, cursor.NewCodec([]byte("test-secret")), clock.Fixed(testNow), time.UTC, logger.NewLogger())
+}
+
+func userNames(t *testing.T, data interface{}) []string {
+	t.Helper()
+	var names []string
+	for _, u := range data.([]models.UserResponse) {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+func TestListUsersCursor_PagesForwardAndBackward(t *testing.T) {
+	svc := newTestService(newFakeRepository("a", "b", "c", "d", "e"))
+	ctx := context.Background()
+
+	first, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListUsersCursor() error = %v", err)
+	}
+	if got := userNames(t, first.Data); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("first page = %v, expected [a b]", got)
+	}
+	if first.PrevCursor != "" || first.NextCursor == "" {
+		t.Fatalf("first page cursors = prev %q next %q", first.PrevCursor, first.NextCursor)
+	}
+
+	second, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("ListUsersCursor() error = %v", err)
+	}
+	if got := userNames(t, second.Data); len(got) != 2 || got[0] != "c" || got[1] != "d" {
+		t.Fatalf("second page = %v, expected [c d]", got)
+	}
+
+	back, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 2, Cursor: second.PrevCursor})
+	if err != nil {
+		t.Fatalf("ListUsersCursor() error = %v", err)
+	}
+	if got := userNames(t, back.Data); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("previous page = %v, expected [a b]", got)
+	}
+	if back.PrevCursor != "" {
+		t.Errorf("Expected no prev_cursor on the first page, got %q", back.PrevCursor)
+	}
+
+	last, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 2, Cursor: second.NextCursor})
+	if err != nil {
+		t.Fatalf("ListUsersCursor() error = %v", err)
+	}
+	if got := userNames(t, last.Data); len(got) != 1 || got[0] != "e" {
+		t.Fatalf("last page = %v, expected [e]", got)
+	}
+	if last.NextCursor != "" {
+		t.Errorf("Expected no next_cursor on the last page, got %q", last.NextCursor)
+	}
+}
+
+func TestListUsersCursor_RejectsCursorForDifferentSort(t *testing.T) {
+	svc := newTestService(newFakeRepository("a", "b", "c"))
+	ctx := context.Background()
+
+	first, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListUsersCursor() error = %v", err)
+	}
+
+	_, err = svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 1, Sort: "name", Cursor: first.NextCursor})
+	if !errors.Is(err, cursor.ErrInvalidCursor) {
+		t.Errorf("ListUsersCursor() error = %v, expected ErrInvalidCursor", err)
+	}
+}
@@ -1,71 +1,83 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"user-api/internal/clock"
+	"user-api/internal/cursor"
+	"user-api/internal/logger"
 	"user-api/internal/models"
 )
 
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
 func TestCalculateAge(t *testing.T) {
 	tests := []struct {
 		name     string
 		dob      time.Time
+		on       time.Time
 		expected int
 	}{
-		{
-			name:     "30 years old - birthday passed",
-			dob:      time.Date(1994, 1, 15, 0, 0, 0, 0, time.UTC),
-			expected: 30, // Assuming current date is after Jan 15
-		},
-		{
-			name:     "25 years old",
-			dob:      time.Date(1999, 6, 20, 0, 0, 0, 0, time.UTC),
-			expected: 25,
-		},
-		{
-			name:     "0 years old - born this year",
-			dob:      time.Now().AddDate(0, -6, 0), // 6 months ago
-			expected: 0,
-		},
+		{"birthday passed", date(1994, 1, 15), date(2024, 6, 15), 30},
+		{"birthday not yet", date(1999, 6, 20), date(2024, 6, 15), 24},
+		{"exact birthday", date(1999, 6, 15), date(2024, 6, 15), 25},
+		{"day before birthday", date(1999, 6, 16), date(2024, 6, 15), 24},
+		{"born today", date(2024, 6, 15), date(2024, 6, 15), 0},
+		{"last day of the year", date(2000, 12, 31), date(2024, 12, 31), 24},
+		// Day-of-year comparisons get these wrong across leap years
+		{"March 1 born in leap year", date(2000, 3, 1), date(2021, 3, 1), 21},
+		{"March 1 in leap year", date(2001, 3, 1), date(2024, 2, 29), 22},
+		{"December 31 born in leap year", date(2000, 12, 31), date(2021, 12, 31), 21},
+		// February 29 birthdays fall on February 28 in common years
+		{"leapling before February 28", date(2000, 2, 29), date(2021, 2, 27), 20},
+		{"leapling on February 28", date(2000, 2, 29), date(2021, 2, 28), 21},
+		{"leapling on February 28 of leap year", date(2000, 2, 29), date(2024, 2, 28), 23},
+		{"leapling on February 29", date(2000, 2, 29), date(2024, 2, 29), 24},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			age := models.CalculateAge(tt.dob)
-			// Note: This test may need adjustment based on the current date
-			// The age calculation depends on whether the birthday has occurred this year
-			if age < 0 {
-				t.Errorf("CalculateAge() returned negative age: %d", age)
+			if age := models.CalculateAge(tt.dob, tt.on); age != tt.expected {
+				t.Errorf("CalculateAge(%s, %s) = %d, expected %d", tt.dob.Format("2006-01-02"), tt.on.Format("2006-01-02"), age, tt.expected)
 			}
 		})
 	}
 }
 
-func TestCalculateAge_ExactBirthday(t *testing.T) {
-	// Test for someone born exactly N years ago today
-	yearsAgo := 25
-	dob := time.Now().AddDate(-yearsAgo, 0, 0)
-	
-	age := models.CalculateAge(dob)
-	
-	if age != yearsAgo {
-		t.Errorf("CalculateAge() = %d, expected %d for exact birthday", age, yearsAgo)
+func TestCalculateAge_Timezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dob := date(2000, 6, 15)
+	now := time.Date(2024, 6, 14, 23, 30, 0, 0, time.UTC)
+
+	if age := models.CalculateAge(dob, now); age != 23 {
+		t.Errorf("CalculateAge() in UTC = %d, expected 23", age)
+	}
+	// It is already June 15 in Tokyo
+	if age := models.CalculateAge(dob, now.In(tokyo)); age != 24 {
+		t.Errorf("CalculateAge() in Asia/Tokyo = %d, expected 24", age)
 	}
 }
 
-func TestCalculateAge_BeforeBirthday(t *testing.T) {
-	// Test for someone whose birthday hasn't occurred yet this year
-	now := time.Now()
-	
-	// Create a DOB that's in the future this year (birthday not yet)
-	futureBirthday := now.AddDate(-30, 0, 30) // 30 years ago + 30 days in future
-	
-	age := models.CalculateAge(futureBirthday)
-	
-	// Should be 29 if birthday hasn't occurred yet
-	if age < 0 {
-		t.Errorf("CalculateAge() should not return negative age: %d", age)
+// TestLatestDOBForAge checks the age filter bound agrees with CalculateAge
+// for every date of birth and reference date around leap years
+func TestLatestDOBForAge(t *testing.T) {
+	for on := date(2023, 1, 1); on.Year() < 2026; on = on.AddDate(0, 0, 1) {
+		for dob := date(1999, 1, 1); dob.Year() < 2002; dob = dob.AddDate(0, 0, 1) {
+			for _, age := range []int{23, 24, 25} {
+				reached := models.CalculateAge(dob, on) >= age
+				if bound := models.LatestDOBForAge(on, age); reached == dob.After(bound) {
+					t.Fatalf("LatestDOBForAge(%s, %d) = %s disagrees with CalculateAge for %s", on.Format("2006-01-02"), age, bound.Format("2006-01-02"), dob.Format("2006-01-02"))
+				}
+			}
+		}
 	}
 }
 
@@ -78,7 +90,7 @@ func TestUserToResponse(t *testing.T) {
 	}
 
 	// Test with age
-	responseWithAge := user.ToResponse(true)
+	responseWithAge := user.ToResponseWithAge(date(2024, 5, 9))
 	if responseWithAge.ID != 1 {
 		t.Errorf("Expected ID 1, got %d", responseWithAge.ID)
 	}
@@ -88,13 +100,122 @@ func TestUserToResponse(t *testing.T) {
 	if responseWithAge.DOB != "1990-05-10" {
 		t.Errorf("Expected DOB 1990-05-10, got %s", responseWithAge.DOB)
 	}
-	if responseWithAge.Age <= 0 {
-		t.Errorf("Expected positive age, got %d", responseWithAge.Age)
+	if responseWithAge.Age != 33 {
+		t.Errorf("Expected age 33, got %d", responseWithAge.Age)
 	}
 
 	// Test without age
-	responseWithoutAge := user.ToResponse(false)
+	responseWithoutAge := user.ToResponse()
 	if responseWithoutAge.Age != 0 {
-		t.Errorf("Expected age 0 when includeAge=false, got %d", responseWithoutAge.Age)
+		t.Errorf("Expected age 0 without an age date, got %d", responseWithoutAge.Age)
+	}
+}
+
+func TestGetUser_Timezone(t *testing.T) {
+	repo := newFakeRepository()
+	repo.Create(context.Background(), "Alice", date(2000, 6, 15))
+	// Just before midnight UTC, when it is already June 15 in Tokyo
+	now := time.Date(2024, 6, 14, 23, 30, 0, 0, time.UTC)
+	svc := NewUserService(repo, cursor.NewCodec([]byte("test-secret")), clock.Fixed(now), time.UTC, logger.NewLogger())
+
+	tests := []struct {
+		tz       string
+		expected int
+	}{
+		{"", 23},
+		{"UTC", 23},
+		{"Asia/Tokyo", 24},
+		{"America/New_York", 23},
+	}
+	for _, tt := range tests {
+		user, err := svc.GetUser(context.Background(), 1, &models.GetUserQuery{TZ: tt.tz})
+		if err != nil {
+			t.Fatalf("GetUser(tz=%q) error = %v", tt.tz, err)
+		}
+		if user.Age != tt.expected {
+			t.Errorf("GetUser(tz=%q) age = %d, expected %d", tt.tz, user.Age, tt.expected)
+		}
+	}
+
+	for _, tz := range []string{"Mars/Olympus_Mons", "Local"} {
+		if _, err := svc.GetUser(context.Background(), 1, &models.GetUserQuery{TZ: tz}); !errors.Is(err, ErrInvalidTimezone) {
+			t.Errorf("GetUser(tz=%q) error = %v, expected ErrInvalidTimezone", tz, err)
+		}
+	}
+}
+
+func TestGetUser_AsOf(t *testing.T) {
+	repo := newFakeRepository()
+	repo.Create(context.Background(), "Alice", date(2000, 2, 29))
+	svc := newTestService(repo)
+
+	tests := []struct {
+		asOf     string
+		tz       string
+		expected int
+	}{
+		{"2021-02-27", "", 20},
+		{"2021-02-28", "", 21},
+		{"2024-02-28", "Asia/Tokyo", 23},
+		// Users born after the date have no age
+		{"1999-12-31", "", 0},
+	}
+	for _, tt := range tests {
+		user, err := svc.GetUser(context.Background(), 1, &models.GetUserQuery{AsOf: tt.asOf, TZ: tt.tz})
+		if err != nil {
+			t.Fatalf("GetUser(as_of=%s) error = %v", tt.asOf, err)
+		}
+		if user.Age != tt.expected || user.AgeAsOf != tt.asOf {
+			t.Errorf("GetUser(as_of=%s) = age %d as of %s, expected %d as of %s", tt.asOf, user.Age, user.AgeAsOf, tt.expected, tt.asOf)
+		}
+	}
+
+	// Without as_of the age is calculated on the current date
+	if user, err := svc.GetUser(context.Background(), 1, &models.GetUserQuery{}); err != nil || user.AgeAsOf != "2024-06-15" {
+		t.Errorf("GetUser() = %+v, %v, expected age as of 2024-06-15", user, err)
+	}
+	if _, err := svc.GetUser(context.Background(), 1, &models.GetUserQuery{AsOf: "2021-02-30"}); !errors.Is(err, ErrInvalidAsOf) {
+		t.Errorf("GetUser() error = %v, expected ErrInvalidAsOf", err)
+	}
+}
+
+func TestListUsers_AsOf(t *testing.T) {
+	repo := newFakeRepository("Alice", "Bob")
+	repo.users[1].DOB = date(2003, 7, 1)
+	svc := newTestService(repo)
+
+	// On 2021-07-01 Alice is 31 and Bob turns 18
+	minAge := 18
+	page, err := svc.ListUsers(context.Background(), &models.ListUsersQuery{Page: 1, PageSize: 10, MinAge: &minAge, AsOf: "2021-07-01"})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	users := page.Data.([]models.UserResponse)
+	if len(users) != 2 || users[0].Age != 31 || users[1].Age != 18 || users[1].AgeAsOf != "2021-07-01" {
+		t.Errorf("ListUsers() = %+v, expected Alice 31 and Bob 18 as of 2021-07-01", users)
+	}
+
+	page, err = svc.ListUsers(context.Background(), &models.ListUsersQuery{Page: 1, PageSize: 10, MinAge: &minAge, AsOf: "2021-06-30"})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if names := userNames(t, page.Data); len(names) != 1 || names[0] != "Alice" || page.TotalCount != 1 {
+		t.Errorf("ListUsers() = %v of %d, expected only Alice the day before", names, page.TotalCount)
+	}
+}
+
+func TestListUsersCursor_AsOfScopesCursor(t *testing.T) {
+	svc := newTestService(newFakeRepository("a", "b", "c"))
+	ctx := context.Background()
+
+	first, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 1, AsOf: "2020-01-01"})
+	if err != nil {
+		t.Fatalf("ListUsersCursor() error = %v", err)
+	}
+	if _, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 1, AsOf: "2020-01-01", Cursor: first.NextCursor}); err != nil {
+		t.Errorf("ListUsersCursor() with the same as_of error = %v", err)
+	}
+	if _, err := svc.ListUsersCursor(ctx, &models.ListUsersQuery{Limit: 1, AsOf: "2021-01-01", Cursor: first.NextCursor}); !errors.Is(err, cursor.ErrInvalidCursor) {
+		t.Errorf("ListUsersCursor() with another as_of error = %v, expected ErrInvalidCursor", err)
 	}
 }
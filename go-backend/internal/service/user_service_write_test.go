@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"user-api/internal/clock"
+	"user-api/internal/cursor"
+	"user-api/internal/logger"
+	"user-api/internal/models"
+	"user-api/internal/repository"
+)
+
+func TestUpdateUser_IfMatch(t *testing.T) {
+	repo := newFakeRepository("Alice")
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	current, err := svc.GetUser(ctx, 1, &models.GetUserQuery{})
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+
+	req := &models.UpdateUserRequest{Name: "Bob", DOB: "1990-01-01"}
+	updated, err := svc.UpdateUser(ctx, 1, req, current.ETag)
+	if err != nil {
+		t.Fatalf("UpdateUser() with current ETag error = %v", err)
+	}
+	if updated.ETag == current.ETag {
+		t.Errorf("Expected ETag to change after update")
+	}
+
+	// The original ETag is now stale
+	if _, err := svc.UpdateUser(ctx, 1, req, current.ETag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("UpdateUser() with stale ETag error = %v, expected ErrPreconditionFailed", err)
+	}
+	if err := svc.DeleteUser(ctx, 1, current.ETag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("DeleteUser() with stale ETag error = %v, expected ErrPreconditionFailed", err)
+	}
+
+	if _, err := svc.UpdateUser(ctx, 1, req, "*"); err != nil {
+		t.Errorf("UpdateUser() with If-Match * error = %v", err)
+	}
+}
+
+func TestGetUser_ETagVariesWithAgeDate(t *testing.T) {
+	repo := newFakeRepository("Alice")
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	get := func(asOf string) *models.UserResponse {
+		t.Helper()
+		user, err := svc.GetUser(ctx, 1, &models.GetUserQuery{AsOf: asOf})
+		if err != nil {
+			t.Fatalf("GetUser(as_of=%s) error = %v", asOf, err)
+		}
+		return user
+	}
+
+	// The age and age_as_of differ, so a cached body must not be revalidated
+	first, again, next := get("2024-06-15"), get("2024-06-15"), get("2024-06-16")
+	if first.ETag != again.ETag {
+		t.Errorf("ETag = %s then %s for the same date, expected them equal", first.ETag, again.ETag)
+	}
+	if first.ETag == next.ETag {
+		t.Errorf("ETag = %s on both dates, expected it to change with age_as_of", first.ETag)
+	}
+
+	// Either representation names the stored version for writes
+	if err := svc.DeleteUser(ctx, 1, next.ETag); err != nil {
+		t.Errorf("DeleteUser() with a GET ETag error = %v", err)
+	}
+}
+
+func TestDeleteUser_SoftDeleteAndRestore(t *testing.T) {
+	repo := newFakeRepository("Alice", "Bob")
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	if err := svc.DeleteUser(ctx, 1, ""); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := svc.GetUser(ctx, 1, &models.GetUserQuery{}); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("GetUser() of deleted user error = %v, expected ErrUserNotFound", err)
+	}
+	deleted, err := svc.GetUser(ctx, 1, &models.GetUserQuery{IncludeDeleted: true})
+	if err != nil || deleted.DeletedAt == nil {
+		t.Fatalf("GetUser() with includeDeleted = %+v, %v", deleted, err)
+	}
+
+	restored, err := svc.RestoreUser(ctx, 1, "")
+	if err != nil {
+		t.Fatalf("RestoreUser() error = %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("Expected restored user to have no deleted_at")
+	}
+	if _, err := svc.RestoreUser(ctx, 1, ""); !errors.Is(err, repository.ErrUserNotDeleted) {
+		t.Errorf("RestoreUser() of active user error = %v, expected ErrUserNotDeleted", err)
+	}
+}
+
+func TestRestoreUser_IfMatch(t *testing.T) {
+	repo := newFakeRepository("Alice")
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	if err := svc.DeleteUser(ctx, 1, ""); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	deleted, err := svc.GetUser(ctx, 1, &models.GetUserQuery{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetUser() with includeDeleted error = %v", err)
+	}
+
+	if _, err := svc.RestoreUser(ctx, 1, `"stale"`); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("RestoreUser() with stale ETag error = %v, expected ErrPreconditionFailed", err)
+	}
+	// The deleted user's ETag is compared, not a 404 for the missing active user
+	restored, err := svc.RestoreUser(ctx, 1, deleted.ETag)
+	if err != nil {
+		t.Fatalf("RestoreUser() with deleted ETag error = %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("Expected restored user to have no deleted_at")
+	}
+	if _, err := svc.RestoreUser(ctx, 2, "*"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("RestoreUser() of missing user error = %v, expected ErrUserNotFound", err)
+	}
+}
+
+// countingRepository counts the reads of a fakeRepository. Like Postgres,
+// it returns copies, so later writes do not change what was read.
+type countingRepository struct {
+	*fakeRepository
+	reads int
+}
+
+func (r *countingRepository) GetByID(ctx context.Context, id int64, includeDeleted bool) (*models.User, error) {
+	r.reads++
+	user, err := r.fakeRepository.GetByID(ctx, id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *user
+	return &snapshot, nil
+}
+
+func TestPatchUser_RetriesOnFreshSnapshot(t *testing.T) {
+	repo := &countingRepository{fakeRepository: newFakeRepository("Alice")}
+	svc := NewUserService(repo, cursor.NewCodec([]byte("test-secret")), clock.Fixed(testNow), time.UTC, logger.NewLogger())
+	ctx := context.Background()
+
+	// The first snapshot is overwritten before the patch is written, so the
+	// patch must be recomputed from the second
+	var seen []string
+	build := func(current models.UserResponse) (*models.PatchUserRequest, error) {
+		seen = append(seen, current.Name)
+		if len(seen) == 1 {
+			name := "Bob"
+			repo.fakeRepository.Patch(ctx, 1, models.UserPatch{Name: &name}, nil)
+		}
+		name := current.Name + "!"
+		return &models.PatchUserRequest{Name: &name}, nil
+	}
+
+	user, err := svc.PatchUser(ctx, 1, build, "")
+	if err != nil {
+		t.Fatalf("PatchUser() error = %v", err)
+	}
+	if user.Name != "Bob!" {
+		t.Errorf("PatchUser() name = %q, expected the patch applied to the concurrent write", user.Name)
+	}
+	if !reflect.DeepEqual(seen, []string{"Alice", "Bob"}) {
+		t.Errorf("build() saw %v, expected [Alice Bob]", seen)
+	}
+	if repo.reads != len(seen) {
+		t.Errorf("GetByID() calls = %d, expected one per attempt (%d)", repo.reads, len(seen))
+	}
+
+	// With an If-Match the concurrent write is reported instead
+	seen = nil
+	current, _ := repo.fakeRepository.GetByID(ctx, 1, false)
+	if _, err := svc.PatchUser(ctx, 1, build, current.ETag()); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("PatchUser() with If-Match error = %v, expected ErrPreconditionFailed", err)
+	}
+}
+
+func TestPatchUser_Rejected(t *testing.T) {
+	repo := newFakeRepository("Alice")
+	svc := newTestService(repo)
+	refused := errors.New("refused")
+
+	_, err := svc.PatchUser(context.Background(), 1, func(models.UserResponse) (*models.PatchUserRequest, error) {
+		return nil, refused
+	}, "")
+	if !errors.Is(err, ErrPatchRejected) || !errors.Is(err, refused) {
+		t.Errorf("PatchUser() error = %v, expected ErrPatchRejected wrapping the build error", err)
+	}
+	if repo.users[0].Name != "Alice" {
+		t.Errorf("Rejected patch changed the user to %q", repo.users[0].Name)
+	}
+}
@@ -0,0 +1,199 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes recording how many rows a statement touched
+const (
+	attrRowsAffected = attribute.Key("db.rows_affected")
+	attrRowsReturned = attribute.Key("db.rows_returned")
+)
+
+// queryName matches the name sqlc embeds in generated statements
+var queryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// WrapConnector returns a connector whose connections start a span for
+// every query and statement, recording the statement and the number of
+// rows returned or affected. Pass the result to sql.OpenDB.
+func WrapConnector(c driver.Connector) driver.Connector {
+	return &connector{Connector: c}
+}
+
+type connector struct {
+	driver.Connector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc}, nil
+}
+
+// conn traces the context-aware query methods. Other calls, including
+// prepared statements, are passed through untraced.
+type conn struct {
+	driver.Conn
+}
+
+var (
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+)
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startQuerySpan(ctx, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	if err == nil {
+		if affected, rowsErr := result.RowsAffected(); rowsErr == nil {
+			span.SetAttributes(attrRowsAffected.Int64(affected))
+		}
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// tracedRows ends the query span once the rows are closed, recording how
+// many were read
+type tracedRows struct {
+	driver.Rows
+	span  trace.Span
+	count int64
+	err   error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.span.SetAttributes(attrRowsReturned.Int64(r.count))
+	endSpan(r.span, errors.Join(r.err, err))
+	return err
+}
+
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return typed.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return typed.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// startQuerySpan starts a client span named after the sqlc query, or the
+// SQL verb for hand-built statements
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return Start(ctx, "db "+statementName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+func statementName(query string) string {
+	query = strings.TrimSpace(query)
+	if m := queryName.FindStringSubmatch(query); m != nil {
+		return m[1]
+	}
+	if verb, _, _ := strings.Cut(query, " "); verb != "" {
+		return strings.ToUpper(verb)
+	}
+	return "query"
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
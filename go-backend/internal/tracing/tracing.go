@@ -0,0 +1,46 @@
+// Package tracing sets up OpenTelemetry tracing and starts spans that
+// follow a request from the HTTP server down to the database.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this application
+const instrumentationName = "user-api"
+
+// LocalsKey is the key under which the request span is stored. Like
+// logger.LocalsKey it is a plain string, so a span stored with Fiber's
+// c.Locals is found through the request context passed to the services.
+const LocalsKey = "span"
+
+// Tracer returns the application tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span as a child of the current span in ctx, which may be
+// the request span stored in c.Locals
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if span, ok := ctx.Value(LocalsKey).(trace.Span); ok {
+			ctx = trace.ContextWithSpan(ctx, span)
+		}
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// SpanContextFromContext returns the span context of the current span in
+// ctx, falling back to the request span stored in c.Locals
+func SpanContextFromContext(ctx context.Context) trace.SpanContext {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc
+	}
+	if span, ok := ctx.Value(LocalsKey).(trace.Span); ok {
+		return span.SpanContext()
+	}
+	return trace.SpanContext{}
+}
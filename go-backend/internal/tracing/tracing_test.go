@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newRecorder installs a provider recording every span
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// fakeConnector hands out connections answering every query with three
+// rows and every statement with two affected rows
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{left: 3}, nil
+}
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(2), nil
+}
+
+type fakeRows struct{ left int }
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	r.left--
+	dest[0] = int64(r.left)
+	return nil
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestWrapConnector(t *testing.T) {
+	recorder := newRecorder(t)
+	db := sql.OpenDB(WrapConnector(fakeConnector{}))
+	defer db.Close()
+
+	ctx, parent := Start(context.Background(), "parent")
+	rows, err := db.QueryContext(ctx, "-- name: ListAPIKeys :many\nSELECT id FROM api_keys")
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if _, err := db.ExecContext(ctx, "DELETE FROM users WHERE deleted_at IS NOT NULL"); err != nil {
+		t.Fatalf("ExecContext() error = %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	query, exec := spans[0], spans[1]
+	if query.Name() != "db ListAPIKeys" || attr(query, attrRowsReturned).AsInt64() != 3 {
+		t.Errorf("query span = %s with %v rows returned", query.Name(), attr(query, attrRowsReturned))
+	}
+	if exec.Name() != "db DELETE" || attr(exec, attrRowsAffected).AsInt64() != 2 {
+		t.Errorf("exec span = %s with %v rows affected", exec.Name(), attr(exec, attrRowsAffected))
+	}
+	if attr(exec, "db.statement").AsString() == "" {
+		t.Error("Expected the statement to be recorded")
+	}
+	for _, span := range spans[:2] {
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of the caller's span", span.Name())
+		}
+	}
+}
+
+func TestStart_RequestSpanInLocals(t *testing.T) {
+	recorder := newRecorder(t)
+	_, request := Tracer().Start(context.Background(), "request")
+
+	// A plain string key reaches values stored in Fiber's c.Locals
+	ctx := context.WithValue(context.Background(), LocalsKey, request)
+	if got := SpanContextFromContext(ctx); got.SpanID() != request.SpanContext().SpanID() {
+		t.Errorf("SpanContextFromContext() = %v, expected the request span", got.SpanID())
+	}
+
+	_, child := Start(ctx, "child")
+	child.End()
+	request.End()
+
+	if got := recorder.Ended()[0].Parent().SpanID(); got != request.SpanContext().SpanID() {
+		t.Errorf("child parent = %v, expected the request span", got)
+	}
+}
@@ -0,0 +1,333 @@
+// Package validation validates request structs and translates validation
+// errors into the language negotiated from Accept-Language. Messages come
+// from JSON files, one per locale, mapping message keys to texts with {0}
+// for the field path and {1} for the rule parameter. The embedded files can
+// be extended or overridden by files in a directory at startup.
+package validation
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/hi"
+	"github.com/go-playground/locales/it"
+	"github.com/go-playground/locales/nl"
+	"github.com/go-playground/locales/pt"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+var (
+	ErrUnsupportedLocale   = errors.New("unsupported locale")
+	ErrInvalidTranslations = errors.New("invalid translation file")
+)
+
+// DefaultLocale is used when Accept-Language matches no supported locale;
+// its file must define every message key
+const DefaultLocale = "en"
+
+// Message keys besides the validation tags
+const (
+	KeyString       = "string"
+	KeyReadOnly     = "read_only"
+	KeyUnknownField = "unknown_field"
+	// KeyInvalid is used for validation tags without their own message
+	KeyInvalid = "invalid"
+)
+
+// translatedTags are the validation tags with their own messages. min and
+// max have one message per kind of value: string, number or items.
+var translatedTags = []string{"required", "min", "max", "datetime", "oneof"}
+
+// localeTranslators lists the locales with their own plural and number
+// rules. Translation files for other locales use the default locale's rules.
+var localeTranslators = map[string]func() locales.Translator{
+	"de": de.New,
+	"en": en.New,
+	"es": es.New,
+	"fr": fr.New,
+	"hi": hi.New,
+	"it": it.New,
+	"nl": nl.New,
+	"pt": pt.New,
+}
+
+// dateLayouts shows Go date layouts in the form clients know
+var dateLayouts = map[string]string{
+	"2006-01-02": "YYYY-MM-DD",
+}
+
+//go:embed translations/*.json
+var translationFS embed.FS
+
+// Validator validates structs and translates their validation errors
+type Validator struct {
+	validate *validator.Validate
+	uni      *ut.UniversalTranslator
+	locales  []string
+	matcher  language.Matcher
+}
+
+// New creates a Validator with the embedded translations. Files named
+// <locale>.json in dir, when dir is not empty, add locales or override
+// individual messages; missing messages fall back to the default locale.
+func New(dir string) (*Validator, error) {
+	messages, err := loadMessages(translationFS, "translations")
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		extra, err := loadMessages(os.DirFS(dir), ".")
+		if err != nil {
+			return nil, err
+		}
+		for locale, texts := range extra {
+			if messages[locale] == nil {
+				messages[locale] = make(map[string]string)
+			}
+			for key, text := range texts {
+				messages[locale][key] = text
+			}
+		}
+	}
+
+	v := &Validator{
+		validate: validator.New(),
+		uni:      ut.New(en.New()),
+	}
+	v.validate.RegisterTagNameFunc(fieldName)
+
+	// The default locale comes first so the matcher falls back to it
+	v.locales = make([]string, 0, len(messages))
+	for locale := range messages {
+		if locale != DefaultLocale {
+			v.locales = append(v.locales, locale)
+		}
+	}
+	sort.Strings(v.locales)
+	v.locales = append([]string{DefaultLocale}, v.locales...)
+
+	tags := make([]language.Tag, len(v.locales))
+	for i, locale := range v.locales {
+		tags[i] = language.Make(locale)
+		if err := v.register(locale, messages[DefaultLocale], messages[locale]); err != nil {
+			return nil, err
+		}
+	}
+	v.matcher = language.NewMatcher(tags)
+
+	return v, nil
+}
+
+// namedTranslator applies the rules of another locale under a new name
+type namedTranslator struct {
+	locales.Translator
+	locale string
+}
+
+// Locale returns the name the translator was registered under
+func (t namedTranslator) Locale() string {
+	return t.locale
+}
+
+// newTranslator returns the translator of a locale, falling back to the
+// default locale's rules for valid BCP 47 tags without their own
+func newTranslator(locale string) (locales.Translator, error) {
+	if newLocale, ok := localeTranslators[locale]; ok {
+		return newLocale(), nil
+	}
+	if _, err := language.Parse(locale); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLocale, locale)
+	}
+	return namedTranslator{Translator: localeTranslators[DefaultLocale](), locale: locale}, nil
+}
+
+// register adds the messages of a locale and registers its translations
+func (v *Validator) register(locale string, defaults, texts map[string]string) error {
+	translator, err := newTranslator(locale)
+	if err != nil {
+		return err
+	}
+	if err := v.uni.AddTranslator(translator, true); err != nil {
+		return err
+	}
+	trans, _ := v.uni.GetTranslator(locale)
+
+	for key, text := range defaults {
+		if localized, ok := texts[key]; ok {
+			text = localized
+		}
+		if err := trans.Add(key, text, true); err != nil {
+			return fmt.Errorf("%w: %s: %s: %v", ErrInvalidTranslations, locale, key, err)
+		}
+	}
+
+	for _, tag := range translatedTags {
+		err := v.validate.RegisterTranslation(tag, trans, func(ut.Translator) error {
+			return nil
+		}, translate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Struct validates a struct by its validate tags
+func (v *Validator) Struct(s interface{}) error {
+	return v.validate.Struct(s)
+}
+
+// Locales returns the supported locales, the default first
+func (v *Validator) Locales() []string {
+	return v.locales
+}
+
+// Negotiate returns the supported locale that best matches an
+// Accept-Language header, for example de for "de-CH, en;q=0.5"
+func (v *Validator) Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := v.matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return v.locales[index]
+}
+
+// Errors translates validation errors into messages keyed by the JSON path
+// of the field, such as name or scopes[0]. It returns nil for errors not
+// returned by Struct.
+func (v *Validator) Errors(err error, locale string) map[string]string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	trans := v.translator(locale)
+	messages := make(map[string]string, len(validationErrors))
+	for _, fe := range validationErrors {
+		field := fieldPath(fe)
+		if hasTranslation(fe.Tag()) {
+			messages[field] = fe.Translate(trans)
+		} else {
+			messages[field] = message(trans, KeyInvalid, field)
+		}
+	}
+	return messages
+}
+
+// Message returns the message for key in locale with the given parameters
+func (v *Validator) Message(locale, key, field string, params ...string) string {
+	return message(v.translator(locale), key, field, params...)
+}
+
+func (v *Validator) translator(locale string) ut.Translator {
+	trans, _ := v.uni.GetTranslator(locale)
+	return trans
+}
+
+// translate builds the message for a failed rule with a registered tag
+func translate(trans ut.Translator, fe validator.FieldError) string {
+	key, param := fe.Tag(), fe.Param()
+	switch key {
+	case "min", "max":
+		key += "." + sizeKind(fe.Kind())
+	case "datetime":
+		if display, ok := dateLayouts[param]; ok {
+			param = display
+		}
+	}
+	return message(trans, key, fieldPath(fe), param)
+}
+
+func message(trans ut.Translator, key, field string, params ...string) string {
+	text, err := trans.T(key, append([]string{field}, params...)...)
+	if err != nil {
+		return field + " is invalid"
+	}
+	return text
+}
+
+func hasTranslation(tag string) bool {
+	for _, t := range translatedTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeKind names what min and max measure for a kind of value
+func sizeKind(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
+	default:
+		return "number"
+	}
+}
+
+// fieldName names struct fields by their json tag, or their query tag for
+// query parameter structs
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "query"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// fieldPath strips the validated struct's name from the error namespace
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// loadMessages reads every <locale>.json file in dir
+func loadMessages(fsys fs.FS, dir string) (map[string]map[string]string, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var texts map[string]string
+		if err := json.Unmarshal(data, &texts); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTranslations, file, err)
+		}
+		messages[strings.TrimSuffix(path.Base(file), ".json")] = texts
+	}
+	return messages, nil
+}
@@ -0,0 +1,210 @@
+package validation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"user-api/internal/models"
+)
+
+func newValidator(t *testing.T, dir string) *Validator {
+	t.Helper()
+	v, err := New(dir)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return v
+}
+
+func TestNegotiate(t *testing.T) {
+	v := newValidator(t, "")
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"de-CH, en;q=0.5", "de"},
+		{"fr-CA", "fr"},
+		{"es-419", "es"},
+		{"hi-IN", "hi"},
+		{"ja, de;q=0.3", "de"},
+		{"ja", "en"},
+		{"not a header;;", "en"},
+	}
+	for _, tt := range tests {
+		if got := v.Negotiate(tt.header); got != tt.expected {
+			t.Errorf("Negotiate(%q) = %q, expected %q", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	v := newValidator(t, "")
+
+	err := v.Struct(&models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"users:read", "users:admin"}})
+	expected := map[string]string{"scopes[1]": "scopes[1] must be one of [users:read users:write users:delete api_keys:manage]"}
+	if got := v.Errors(err, "en"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Errors() = %v, expected %v", got, expected)
+	}
+
+	err = v.Struct(&models.CreateUserRequest{DOB: "10/05/1990"})
+	expected = map[string]string{
+		"name": "name ist erforderlich",
+		"dob":  "dob muss ein Datum im Format YYYY-MM-DD sein",
+	}
+	if got := v.Errors(err, "de"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Errors() = %v, expected %v", got, expected)
+	}
+
+	minAge := 200
+	err = v.Struct(&models.ListUsersQuery{Page: 1, PageSize: 10, MinAge: &minAge})
+	expected = map[string]string{"min_age": "min_age doit être inférieur ou égal à 150"}
+	if got := v.Errors(err, "fr"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Errors() = %v, expected %v", got, expected)
+	}
+
+	if got := v.Errors(errors.New("boom"), "en"); got != nil {
+		t.Errorf("Errors() = %v, expected nil for other errors", got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	v := newValidator(t, "")
+	if got := v.Message("es", KeyReadOnly, "id"); got != "id es de solo lectura" {
+		t.Errorf("Message() = %q", got)
+	}
+}
+
+// TestTranslations_Complete checks every built-in locale translates every
+// message and every validation tag used by the models
+func TestTranslations_Complete(t *testing.T) {
+	messages, err := loadMessages(translationFS, "translations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, locale := range []string{"en", "de", "fr", "es", "hi"} {
+		if messages[locale] == nil {
+			t.Errorf("no translations for %s", locale)
+		}
+	}
+	for locale, texts := range messages {
+		for key := range messages[DefaultLocale] {
+			if texts[key] == "" {
+				t.Errorf("%s.json is missing %q", locale, key)
+			}
+		}
+	}
+
+	skipped := map[string]bool{"omitempty": true, "omitnil": true, "dive": true}
+	for _, model := range []interface{}{
+		models.CreateUserRequest{},
+		models.UpdateUserRequest{},
+		models.PatchUserRequest{},
+		models.PaginationQuery{},
+		models.ListUsersQuery{},
+		models.GetUserQuery{},
+		models.BirthdaysQuery{},
+		models.CreateAPIKeyRequest{},
+	} {
+		typ := reflect.TypeOf(model)
+		for i := 0; i < typ.NumField(); i++ {
+			for _, rule := range strings.Split(typ.Field(i).Tag.Get("validate"), ",") {
+				tag := strings.SplitN(rule, "=", 2)[0]
+				if tag != "" && !skipped[tag] && !hasTranslation(tag) {
+					t.Errorf("%s.%s uses untranslated tag %q", typ.Name(), typ.Field(i).Name, tag)
+				}
+			}
+		}
+	}
+}
+
+func TestNew_Directory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"en.json": `{"required": "{0} is mandatory"}`,
+		"it.json": `{"required": "{0} è obbligatorio"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := newValidator(t, dir)
+	if locale := v.Negotiate("it-IT"); locale != "it" {
+		t.Fatalf("Negotiate() = %q, expected it", locale)
+	}
+	err := v.Struct(&models.CreateUserRequest{DOB: "1990-05-10"})
+	if got := v.Errors(err, "en")["name"]; got != "name is mandatory" {
+		t.Errorf("overridden message = %q", got)
+	}
+	if got := v.Errors(err, "it")["name"]; got != "name è obbligatorio" {
+		t.Errorf("added message = %q", got)
+	}
+	// Messages missing from an added locale fall back to the default locale
+	if got := v.Message("it", KeyReadOnly, "id"); got != "id is read-only" {
+		t.Errorf("fallback message = %q", got)
+	}
+}
+
+// TestNew_LocaleWithoutRules checks translation files can add locales that
+// have no compiled-in rules
+func TestNew_LocaleWithoutRules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ja.json": `{"required": "{0}は必須です"}`,
+		"pl.json": `{"required": "{0} jest wymagane"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := newValidator(t, dir)
+	if locale := v.Negotiate("ja-JP, en;q=0.5"); locale != "ja" {
+		t.Fatalf("Negotiate() = %q, expected ja", locale)
+	}
+	if locale := v.Negotiate("pl"); locale != "pl" {
+		t.Fatalf("Negotiate() = %q, expected pl", locale)
+	}
+	err := v.Struct(&models.CreateUserRequest{DOB: "10/05/1990"})
+	expected := map[string]string{
+		"name": "nameは必須です",
+		"dob":  "dob must be a date in the format YYYY-MM-DD",
+	}
+	if got := v.Errors(err, "ja"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Errors() = %v, expected %v", got, expected)
+	}
+	if got := v.Errors(err, "pl")["name"]; got != "name jest wymagane" {
+		t.Errorf("added message = %q", got)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected error
+	}{
+		{"unsupported locale", "xx.json", `{}`, ErrUnsupportedLocale},
+		{"malformed file", "de.json", `{"required":`, ErrInvalidTranslations},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := New(dir); !errors.Is(err, tt.expected) {
+				t.Errorf("New() error = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}
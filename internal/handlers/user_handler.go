@@ -3,14 +3,33 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go-user-api/internal/models"
 	"go-user-api/internal/service"
 )
 
+// Clock reports the current time
+type Clock func() time.Time
+
+// UserHandler serves the user routes, calculating ages on the UTC date
+// reported by its clock
+type UserHandler struct {
+	clock Clock
+}
+
+// NewUserHandler creates a UserHandler reading the date from clock
+func NewUserHandler(clock Clock) *UserHandler {
+	return &UserHandler{clock: clock}
+}
+
+func (h *UserHandler) today() time.Time {
+	return h.clock().UTC()
+}
+
 // POST /users
-func CreateUser(c *gin.Context) {
+func (h *UserHandler) CreateUser(c *gin.Context) {
 	var user models.User
 
 	if err := c.ShouldBindJSON(&user); err != nil {
@@ -20,12 +39,12 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	createdUser := service.CreateUser(user)
+	createdUser := service.CreateUser(user, h.today())
 	c.JSON(http.StatusCreated, createdUser)
 }
 
 // GET /users/:id
-func GetUserByID(c *gin.Context) {
+func (h *UserHandler) GetUserByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 
@@ -36,7 +55,7 @@ func GetUserByID(c *gin.Context) {
 		return
 	}
 
-	user, found := service.GetUserByID(id)
+	user, found := service.GetUserByID(id, h.today())
 	if !found {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "User not found",
@@ -46,7 +65,7 @@ func GetUserByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
-func GetAllUsers(c *gin.Context) {
-	users := service.GetAllUsers()
+func (h *UserHandler) GetAllUsers(c *gin.Context) {
+	users := service.GetAllUsers(h.today())
 	c.JSON(200, users)
 }
@@ -5,8 +5,8 @@ import (
 	"go-user-api/internal/handlers"
 )
 
-func UserRoutes(r *gin.Engine) {
-	r.POST("/users", handlers.CreateUser)
-	r.GET("/users/:id", handlers.GetUserByID)
-	r.GET("/users", handlers.GetAllUsers)
+func UserRoutes(r *gin.Engine, h *handlers.UserHandler) {
+	r.POST("/users", h.CreateUser)
+	r.GET("/users/:id", h.GetUserByID)
+	r.GET("/users", h.GetAllUsers)
 }
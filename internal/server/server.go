@@ -0,0 +1,44 @@
+// Package server runs the HTTP server until shutdown and drains in-flight
+// requests before returning.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Run serves handler on ln until ctx is done, then stops accepting
+// connections and waits up to timeout for in-flight requests to complete.
+// Run returns an error if the server fails or requests are still running
+// after timeout.
+func Run(ctx context.Context, handler http.Handler, ln net.Listener, timeout time.Duration) error {
+	srv := &http.Server{Handler: handler}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	log.Println("Shutting down, waiting for in-flight requests")
+
+	// stop accepting connections and wait for in-flight requests
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain in-flight requests: %w", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
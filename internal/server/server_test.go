@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testServer runs a server whose /slow route blocks until release is closed
+type testServer struct {
+	addr    string
+	started chan struct{}
+	release chan struct{}
+	stop    context.CancelFunc
+	done    chan error
+}
+
+func startServer(t *testing.T, timeout time.Duration) *testServer {
+	t.Helper()
+	ts := &testServer{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+		done:    make(chan error, 1),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(ts.started)
+		<-ts.release
+		io.WriteString(w, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	ts.addr = ln.Addr().String()
+
+	var ctx context.Context
+	ctx, ts.stop = context.WithCancel(context.Background())
+	go func() {
+		ts.done <- Run(ctx, mux, ln, timeout)
+	}()
+	return ts
+}
+
+func TestRun_CompletesInFlightRequest(t *testing.T) {
+	ts := startServer(t, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ts.addr + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+
+	<-ts.started
+	ts.stop()
+
+	// Shutdown waits for the request instead of returning
+	select {
+	case err := <-ts.done:
+		t.Fatalf("Run() returned %v with a request in flight", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := net.DialTimeout("tcp", ts.addr, 100*time.Millisecond); err == nil {
+		t.Error("new connection accepted after shutdown started")
+	}
+
+	close(ts.release)
+	if got := <-responses; got.err != nil || got.body != "done" {
+		t.Errorf("in-flight response = %q, %v, expected done", got.body, got.err)
+	}
+	if err := <-ts.done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	ts := startServer(t, 50*time.Millisecond)
+	defer close(ts.release)
+
+	go http.Get("http://" + ts.addr + "/slow")
+	<-ts.started
+	ts.stop()
+
+	select {
+	case err := <-ts.done:
+		if err == nil {
+			t.Error("Run() error = nil, expected the drain to time out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after the timeout")
+	}
+}
@@ -7,44 +7,55 @@ import (
 	"go-user-api/internal/repository"
 )
 
-// helper function to calculate age
-func calculateAge(dob string) int {
+// helper function to calculate age on the given date. Birthdays are compared
+// by month and day, and February 29 birthdays fall on February 28 in common
+// years.
+func calculateAge(dob string, today time.Time) int {
 	layout := "2006-01-02"
 	birthDate, err := time.Parse(layout, dob)
 	if err != nil {
 		return 0
 	}
 
-	now := time.Now()
-	age := now.Year() - birthDate.Year()
+	year, month, day := today.Date()
+	age := year - birthDate.Year()
+
+	// February 29 rolls over into March in common years
+	birthMonth, birthDay := birthDate.Month(), birthDate.Day()
+	if time.Date(year, birthMonth, birthDay, 0, 0, 0, 0, time.UTC).Month() != birthMonth {
+		birthDay--
+	}
 
 	// adjust if birthday hasn't occurred yet this year
-	if now.YearDay() < birthDate.YearDay() {
+	if month < birthMonth || (month == birthMonth && day < birthDay) {
 		age--
 	}
 
 	return age
 }
 
-func CreateUser(user models.User) models.User {
-	user.Age = calculateAge(user.DOB)
+// CreateUser stores a user and calculates their age on today
+func CreateUser(user models.User, today time.Time) models.User {
+	user.Age = calculateAge(user.DOB, today)
 	return repository.CreateUser(user)
 }
 
-func GetAllUsers() []models.User {
+// GetAllUsers lists users with their ages on today
+func GetAllUsers(today time.Time) []models.User {
 	users := repository.GetAllUsers()
 
 	for i := range users {
-		users[i].Age = calculateAge(users[i].DOB)
+		users[i].Age = calculateAge(users[i].DOB, today)
 	}
 
 	return users
 }
 
-func GetUserByID(id int) (models.User, bool) {
+// GetUserByID finds a user and calculates their age on today
+func GetUserByID(id int, today time.Time) (models.User, bool) {
 	user, found := repository.GetUserByID(id)
 	if found {
-		user.Age = calculateAge(user.DOB)
+		user.Age = calculateAge(user.DOB, today)
 	}
 	return user, found
 }
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateAge(t *testing.T) {
+	tests := []struct {
+		name     string
+		dob      string
+		today    string
+		expected int
+	}{
+		{"birthday passed", "1994-01-15", "2024-06-15", 30},
+		{"birthday not yet", "1999-06-20", "2024-06-15", 24},
+		{"exact birthday", "1999-06-15", "2024-06-15", 25},
+		{"invalid dob", "15/06/1999", "2024-06-15", 0},
+		// Day-of-year comparisons get these wrong across leap years
+		{"March 1 born in leap year", "2000-03-01", "2021-03-01", 21},
+		{"March 1 on February 29", "2001-03-01", "2024-02-29", 22},
+		{"February 28 in leap year", "2001-02-28", "2024-02-28", 23},
+		{"February 28 born in leap year", "2000-02-28", "2021-02-28", 21},
+		// February 29 birthdays fall on February 28 in common years
+		{"leapling on February 27", "2000-02-29", "2021-02-27", 20},
+		{"leapling on February 28", "2000-02-29", "2021-02-28", 21},
+		{"leapling on March 1", "2000-02-29", "2021-03-01", 21},
+		{"leapling on February 28 of leap year", "2000-02-29", "2024-02-28", 23},
+		{"leapling on February 29", "2000-02-29", "2024-02-29", 24},
+		{"leapling on March 1 of leap year", "2000-02-29", "2024-03-01", 24},
+		{"leapling in 2100", "2000-02-29", "2100-02-28", 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			today, err := time.Parse("2006-01-02", tt.today)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if age := calculateAge(tt.dob, today); age != tt.expected {
+				t.Errorf("calculateAge(%s, %s) = %d, expected %d", tt.dob, tt.today, age, tt.expected)
+			}
+		})
+	}
+}